@@ -5,10 +5,22 @@ import "context"
 type Bridge interface {
 	ID() string
 	Name() string
+	DeviceInfo() DeviceInfo
 	Light(ctx context.Context, id string) (Light, error)
 	Lights(ctx context.Context) ([]Light, error)
 	DiscoverLights(ctx context.Context) ([]Light, error)
 	Sensor(ctx context.Context, id string) (Sensor, error)
 	Sensors(ctx context.Context) ([]Sensor, error)
 	DiscoverSensors(ctx context.Context) ([]Sensor, error)
+	SearchDevices(ctx context.Context, search DeviceSearch) <-chan SearchEvent
+	Group(ctx context.Context, id string) (Group, error)
+	Groups(ctx context.Context) ([]Group, error)
+	CreateGroup(ctx context.Context, name string, kind GroupKind, lights []Light) (Group, error)
+	DeleteGroup(ctx context.Context, id string) error
+	Scene(ctx context.Context, id string) (Scene, error)
+	Scenes(ctx context.Context) ([]Scene, error)
+	CreateScene(ctx context.Context, name string, lights []Light) (Scene, error)
+	RecallScene(ctx context.Context, id string) error
+	DeleteScene(ctx context.Context, id string) error
+	Events(ctx context.Context) <-chan Event
 }
@@ -14,6 +14,9 @@ import (
 
 var ErrInvalidInput = errors.New("invalid input")
 
+// nearWhiteDistance is how far from a white in xy coordinates a color can be to be near white.
+const nearWhiteDistance = 0.02
+
 type Color struct {
 	R float64
 	G float64
@@ -66,18 +69,64 @@ func (c *Color) SetHSV(h, s, v float64) {
 	c.K = 0
 }
 
+// ColorFromXY creates a color from CIE 1931 xy coordinates and a brightness.
+func ColorFromXY(x, y, brightness float64) Color {
+	color := Color{}
+	color.SetXY(x, y, brightness)
+
+	return color
+}
+
+// XY gets the CIE 1931 xy coordinates of the color, and its brightness (the HSV value).
+func (c *Color) XY() (x, y, brightness float64) {
+	x, y, _ = (colorful.Color{R: c.R, G: c.G, B: c.B}).Xyy()
+	brightness = math.Max(c.R, math.Max(c.G, c.B))
+
+	return x, y, brightness
+}
+
+// SetXY sets the color from CIE 1931 xy coordinates and a brightness between 0 and 1. Coordinates
+// outside of the sRGB gamut get the closest color with the same hue.
+func (c *Color) SetXY(x, y, brightness float64) {
+	c.K = 0
+
+	if y <= 0 {
+		c.R, c.G, c.B = 0, 0, 0
+		return
+	}
+
+	r, g, b := colorful.XyzToLinearRgb(colorful.XyyToXyz(x, y, 1))
+	r, g, b = math.Max(r, 0), math.Max(g, 0), math.Max(b, 0)
+
+	peak := math.Max(r, math.Max(g, b))
+	if peak <= 0 {
+		c.R, c.G, c.B = 0, 0, 0
+		return
+	}
+
+	// Scaling the linear values keeps the chromaticity, and the brightness ends up as the value.
+	scale, _, _ := (colorful.Color{R: brightness}).LinearRgb()
+	cc := colorful.LinearRgb(r/peak*scale, g/peak*scale, b/peak*scale)
+	c.R = cc.R
+	c.G = cc.G
+	c.B = cc.B
+}
+
 func (c *Color) Hex() string {
 	var data [3]byte
-	data[0] = byte(c.R * 255)
-	data[1] = byte(c.G * 255)
-	data[2] = byte(c.B * 255)
+	data[0] = byte(math.Round(c.R * 255))
+	data[1] = byte(math.Round(c.G * 255))
+	data[2] = byte(math.Round(c.B * 255))
 
 	return hex.EncodeToString(data[:])
 }
 
 func (c *Color) SetHex(hexStr string) error {
-	if hexStr[0] == '#' {
-		hexStr = hexStr[1:]
+	hexStr = strings.TrimPrefix(hexStr, "#")
+
+	// The alpha in AABBCCDD is ignored.
+	if len(hexStr) == 8 {
+		hexStr = hexStr[:6]
 	}
 
 	// ABC -> AABBCC
@@ -142,73 +191,212 @@ func (c *Color) SetKelvin(kelvin int) {
 	c.K = kelvin
 }
 
+// Kelvin gets the color temperature, which is estimated with ApproxKelvin if the color was not set
+// from one.
+func (c *Color) Kelvin() int {
+	if c.K != 0 {
+		return c.K
+	}
+
+	return c.ApproxKelvin()
+}
+
+// ApproxKelvin estimates the correlated color temperature of the color with McCamy's formula. It is
+// only meaningful for colors where IsNearWhite is true, but any color gets the closest white.
+func (c *Color) ApproxKelvin() int {
+	x, y, _ := c.XY()
+
+	n := (x - 0.3320) / (0.1858 - y)
+	kelvin := int(math.Round(449*n*n*n + 3525*n*n + 6823.3*n + 5520.33))
+	if kelvin < 1000 {
+		kelvin = 1000
+	} else if kelvin > 12000 {
+		kelvin = 12000
+	}
+
+	return kelvin
+}
+
+// IsNearWhite returns true if the color is close enough to a white of some color temperature that
+// lights without full color can show it.
+func (c *Color) IsNearWhite() bool {
+	if c.K != 0 {
+		return true
+	}
+
+	white := Color{}
+	white.SetKelvin(c.ApproxKelvin())
+
+	x, y, _ := c.XY()
+	wx, wy, _ := white.XY()
+
+	return math.Hypot(x-wx, y-wy) <= nearWhiteDistance
+}
+
+// SetFromString parses the color. The formats are hex with an optional alpha that is ignored,
+// rgb(r, g, b) with values up to 255 or percentages, hsv(h, s, v), hsl(h, s, l), xy(x, y),
+// kelvin (2700k), mireds (370mired or mired(370)) and the names in CSS, plus a few shades of white
+// like warmwhite.
 func (c *Color) SetFromString(s string) error {
-	isRgbFunc := strings.HasPrefix(s, "rgb(")
-	isHsvFunc := strings.HasPrefix(s, "hsv(")
-	if (isRgbFunc || isHsvFunc) && strings.HasSuffix(s, ")") {
-		argsStr := strings.SplitN(s[4:len(s)-1], ",", 3)
-		args := make([]float64, len(argsStr))
-		if len(args) < 3 {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return ErrInvalidInput
+	}
+
+	if open := strings.IndexByte(s, '('); open > 0 && strings.HasSuffix(s, ")") {
+		args := strings.Split(s[open+1:len(s)-1], ",")
+		for i := range args {
+			args[i] = strings.TrimSpace(args[i])
+		}
+
+		return c.setFromFunc(s[:open], args)
+	}
+
+	if named, ok := constants.NamedColors[s]; ok {
+		return c.SetFromString(named)
+	}
+
+	if strings.HasSuffix(s, "mired") {
+		return c.setMired(strings.TrimSpace(s[:len(s)-5]))
+	}
+
+	if strings.HasSuffix(s, "k") {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return err
+		}
+
+		c.SetKelvin(n)
+		return nil
+	}
+
+	return c.SetHex(s)
+}
+
+// String gets the color in the most specific format SetFromString understands, which is kelvin for
+// colors set from a color temperature and hex for the rest.
+func (c Color) String() string {
+	if c.K != 0 {
+		return strconv.Itoa(c.K) + "k"
+	}
+
+	return "#" + c.Hex()
+}
+
+func (c *Color) setFromFunc(name string, args []string) error {
+	switch name {
+	case "rgb":
+		if len(args) != 3 {
 			return errors.New("invalid rgb(...)")
 		}
 
-		for i, argStr := range argsStr {
-			arg, err := strconv.ParseFloat(strings.Trim(argStr, " \t\r\n "), 64)
+		var values [3]float64
+		for i, arg := range args {
+			value, err := parseColorArg(arg, 255)
 			if err != nil {
 				return err
 			}
+			if value < 0 || value > 255 {
+				return fmt.Errorf("invalid %s: %s (0..255)", [3]string{"red", "green", "blue"}[i], arg)
+			}
 
-			args[i] = arg
+			values[i] = value / 255
 		}
 
-		if isRgbFunc {
-			if args[0] > 255 {
-				return fmt.Errorf("invalid red: %f (0..255)", args[0])
-			}
-			if args[1] > 255 {
-				return fmt.Errorf("invalid green: %f (0..255)", args[0])
+		c.R, c.G, c.B, c.K = values[0], values[1], values[2], 0
+	case "hsv", "hsl":
+		if len(args) != 3 {
+			return fmt.Errorf("invalid %s(...)", name)
+		}
+
+		h, err := parseColorArg(args[0], 360)
+		if err != nil {
+			return err
+		}
+		if h < 0 || h >= 360 {
+			return fmt.Errorf("invalid hue: %s (0..360)", args[0])
+		}
+
+		var values [2]float64
+		for i, arg := range args[1:] {
+			value, err := parseColorArg(arg, 1)
+			if err != nil {
+				return err
 			}
-			if args[2] > 255 {
-				return fmt.Errorf("invalid blue: %f (0..255)", args[0])
+			if value < 0 || value > 1 {
+				return fmt.Errorf("invalid %c: %s (0..1)", name[i+1], arg)
 			}
 
-			c.R = args[0] / 255
-			c.G = args[1] / 255
-			c.B = args[2] / 255
+			values[i] = value
+		}
 
-			return nil
-		} else if isHsvFunc {
-			if args[0] >= 360 {
-				return fmt.Errorf("invalid hue: %f (0..360)", args[0])
-			}
-			if args[1] > 1 {
-				return fmt.Errorf("invalid sat: %f (0..1)", args[0])
+		if name == "hsl" {
+			cc := colorful.Hsl(h, values[0], values[1])
+			c.R, c.G, c.B, c.K = cc.R, cc.G, cc.B, 0
+		} else {
+			c.SetHSV(h, values[0], values[1])
+		}
+	case "xy":
+		if len(args) != 2 {
+			return errors.New("invalid xy(...)")
+		}
+
+		var values [2]float64
+		for i, arg := range args {
+			value, err := parseColorArg(arg, 1)
+			if err != nil {
+				return err
 			}
-			if args[2] > 1 {
-				return fmt.Errorf("invalid value: %f (0..1)", args[0])
+			if value < 0 || value > 1 {
+				return fmt.Errorf("invalid %c: %s (0..1)", name[i], arg)
 			}
 
-			c.SetHSV(args[0], args[1], args[2])
+			values[i] = value
+		}
 
-			return nil
+		c.SetXY(values[0], values[1], 1)
+	case "mired":
+		if len(args) != 1 {
+			return errors.New("invalid mired(...)")
 		}
+
+		return c.setMired(args[0])
+	default:
+		return fmt.Errorf("unknown color function: %s", name)
 	}
 
-	if strings.HasSuffix(s, "k") || strings.HasSuffix(s, "K") {
-		n, err := strconv.Atoi(s[:len(s)-1])
+	return nil
+}
+
+func (c *Color) setMired(s string) error {
+	mired, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	if mired <= 0 {
+		return fmt.Errorf("invalid mired: %s", s)
+	}
+
+	c.SetKelvin(int(math.Round(1000000 / mired)))
+	return nil
+}
+
+// parseColorArg parses a number, or a percentage of max.
+func parseColorArg(s string, max float64) (float64, error) {
+	if strings.HasSuffix(s, "%") {
+		percentage, err := strconv.ParseFloat(strings.TrimSpace(s[:len(s)-1]), 64)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
-		c.SetKelvin(n)
-		return nil
+		return percentage / 100 * max, nil
 	}
 
-	return c.SetHex(s)
+	return strconv.ParseFloat(s, 64)
 }
 
-func (c *Color) MarshalJSON() ([]byte, error) {
-	return []byte(c.Hex()), nil
+func (c Color) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
 }
 
 func (c *Color) UnmarshalJSON(v []byte) error {
@@ -1,6 +1,7 @@
 package lucifer_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/gissleh/lucifer"
 	"github.com/stretchr/testify/assert"
@@ -42,7 +43,7 @@ func TestColor_FromString(t *testing.T) {
 		"abc":                    {r: "aabbcc"},
 		"abcd":                   {e: true},
 		"6000":                   {e: true},
-		"#aaccff00":              {e: true},
+		"#aaccff00":              {r: "aaccff"},
 		"#aabbss":                {e: true},
 		"fuck":                   {e: true},
 		"500k":                   {r: "ff3800", k: 1000},
@@ -53,6 +54,23 @@ func TestColor_FromString(t *testing.T) {
 		"6067k":                  {r: "fff4f1", k: 6067},
 		"12000k":                 {r: "c3d1ff", k: 12000},
 		"10000000k":              {r: "c3d1ff", k: 12000},
+		"rgb(100%, 50%, 0%)":     {r: "ff8000"},
+		"rgb(101%, 50%, 0%)":     {e: true},
+		"hsl(0, 1, 0.5)":         {r: "ff0000"},
+		"hsl(120, 100%, 25%)":    {r: "008000"},
+		"hsl(120, 1, 1.5)":       {e: true},
+		"xy(0.64, 0.33)":         {r: "ff0000"},
+		"xy(0.64)":               {e: true},
+		"mired(370)":             {r: "ffa957", k: 2703},
+		"370mired":               {r: "ffa957", k: 2703},
+		"0mired":                 {e: true},
+		"coral":                  {r: "ff7f50"},
+		"Black":                  {r: "000000"},
+		"warmwhite":              {r: "ffa957", k: 2700},
+		"cmyk(0, 0, 0, 0)":       {e: true},
+		"":                       {e: true},
+		" ":                      {e: true},
+		"#":                      {e: true},
 	}
 
 	for input, output := range table {
@@ -86,6 +104,13 @@ func TestColor_FromString(t *testing.T) {
 	}
 }
 
+func TestParseColor_Empty(t *testing.T) {
+	for _, input := range []string{"", " ", "\t\n"} {
+		_, err := lucifer.ParseColor(input)
+		assert.Equal(t, lucifer.ErrInvalidInput, err, "%q", input)
+	}
+}
+
 func TestColor_HSV(t *testing.T) {
 	table := map[string]struct {
 		h, s, v float64
@@ -137,3 +162,81 @@ func TestColor_SetHSV(t *testing.T) {
 		})
 	}
 }
+
+func TestColor_XY(t *testing.T) {
+	table := []struct {
+		hex  string
+		x, y float64
+	}{
+		{hex: "ff0000", x: 0.64, y: 0.33},
+		{hex: "00ff00", x: 0.3, y: 0.6},
+		{hex: "0000ff", x: 0.15, y: 0.06},
+		{hex: "ffffff", x: 0.3127, y: 0.329},
+	}
+
+	for _, row := range table {
+		t.Run(row.hex, func(t *testing.T) {
+			c := lucifer.MustParseColor(row.hex)
+
+			x, y, brightness := c.XY()
+			assert.InDelta(t, row.x, x, 0.001, "x")
+			assert.InDelta(t, row.y, y, 0.001, "y")
+			assert.InDelta(t, 1, brightness, 0.001, "brightness")
+
+			c2 := lucifer.ColorFromXY(x, y, 0.5)
+			x2, y2, brightness2 := c2.XY()
+			assert.InDelta(t, x, x2, 0.001, "x after SetXY")
+			assert.InDelta(t, y, y2, 0.001, "y after SetXY")
+			assert.InDelta(t, 0.5, brightness2, 0.001, "brightness after SetXY")
+		})
+	}
+}
+
+func TestColor_ApproxKelvin(t *testing.T) {
+	for _, kelvin := range []int{2000, 2700, 4000, 5000, 6500} {
+		t.Run(fmt.Sprintf("%dk", kelvin), func(t *testing.T) {
+			c := lucifer.Color{}
+			c.SetKelvin(kelvin)
+			assert.Equal(t, kelvin, c.Kelvin())
+			assert.True(t, c.IsNearWhite())
+
+			// Losing K, e.g. by going through hex, should give a close estimate.
+			c2 := lucifer.MustParseColor(c.Hex())
+			assert.Equal(t, 0, c2.K)
+			assert.InDelta(t, kelvin, c2.Kelvin(), float64(kelvin)*0.05)
+			assert.True(t, c2.IsNearWhite())
+		})
+	}
+
+	for _, hex := range []string{"ff0000", "00ff00", "0000ff", "ff00ff"} {
+		c := lucifer.MustParseColor(hex)
+		assert.False(t, c.IsNearWhite(), hex)
+	}
+}
+
+func TestColor_String(t *testing.T) {
+	for _, input := range []string{"#ff0000", "#deadba", "2700k", "6500k", "rgb(1, 2, 3)", "warmwhite", "xy(0.3, 0.4)"} {
+		c := lucifer.MustParseColor(input)
+
+		c2 := lucifer.MustParseColor(c.String())
+		assert.Equal(t, c.Hex(), c2.Hex(), input)
+		assert.Equal(t, c.K, c2.K, input)
+	}
+
+	assert.Equal(t, "2700k", lucifer.MustParseColor("warmwhite").String())
+	assert.Equal(t, "#ff7f50", lucifer.MustParseColor("coral").String())
+}
+
+func TestColor_MarshalJSON(t *testing.T) {
+	states := map[string]lucifer.LightState{
+		"a": {Color: lucifer.MustParseColor("2700k")},
+		"b": {Color: lucifer.MustParseColor("#ff0000")},
+	}
+
+	data, err := json.Marshal(states)
+	assert.NoError(t, err)
+
+	result := make(map[string]lucifer.LightState)
+	assert.NoError(t, json.Unmarshal(data, &result))
+	assert.Equal(t, states, result)
+}
@@ -0,0 +1,198 @@
+package lucifer
+
+import (
+	"github.com/lucasb-eyer/go-colorful"
+	"math"
+)
+
+// ColorSpace is a color space that colors can be interpolated in.
+type ColorSpace string
+
+const (
+	// ColorSpaceOklab interpolates with even steps in perceived color. It is the default.
+	ColorSpaceOklab ColorSpace = "oklab"
+	// ColorSpaceLab interpolates in CIE L*a*b*.
+	ColorSpaceLab ColorSpace = "lab"
+	// ColorSpaceHCL interpolates hue, chroma and lightness, which keeps the colors in between saturated.
+	ColorSpaceHCL ColorSpace = "hcl"
+	// ColorSpaceRGB interpolates each channel, which is what lights without a color model do.
+	ColorSpaceRGB ColorSpace = "rgb"
+)
+
+// Lerp gets the color at t between the color (0) and the other (1). Whites set from color
+// temperatures are interpolated in kelvin, and everything else in Oklab.
+func (c *Color) Lerp(other Color, t float64) Color {
+	return c.LerpIn(ColorSpaceOklab, other, t)
+}
+
+// LerpIn is like Lerp, but interpolates in the color space unless both colors are color
+// temperatures.
+func (c *Color) LerpIn(space ColorSpace, other Color, t float64) Color {
+	if t <= 0 {
+		return *c
+	} else if t >= 1 {
+		return other
+	}
+
+	result := Color{}
+	if c.K != 0 && other.K != 0 {
+		result.SetKelvin(c.K + int(math.Round(float64(other.K-c.K)*t)))
+		return result
+	}
+
+	c1 := colorful.Color{R: c.R, G: c.G, B: c.B}
+	c2 := colorful.Color{R: other.R, G: other.G, B: other.B}
+
+	var cc colorful.Color
+	switch space {
+	case ColorSpaceLab:
+		cc = c1.BlendLab(c2, t)
+	case ColorSpaceHCL:
+		h1, ch1, l1 := c1.Hcl()
+		h2, ch2, l2 := c2.Hcl()
+
+		// The hue goes the shortest way around.
+		if h2-h1 > 180 {
+			h1 += 360
+		} else if h1-h2 > 180 {
+			h2 += 360
+		}
+
+		cc = colorful.Hcl(math.Mod(h1+(h2-h1)*t, 360), ch1+(ch2-ch1)*t, l1+(l2-l1)*t)
+	case ColorSpaceRGB:
+		cc = c1.BlendRgb(c2, t)
+	default:
+		l1, a1, b1 := oklab(c1)
+		l2, a2, b2 := oklab(c2)
+		cc = fromOklab(l1+(l2-l1)*t, a1+(a2-a1)*t, b1+(b2-b1)*t)
+	}
+
+	cc = cc.Clamped()
+	result.R, result.G, result.B = cc.R, cc.G, cc.B
+
+	return result
+}
+
+// MixColors mixes the colors the way light from several lights would mix on a surface, by averaging
+// them in linear RGB.
+func MixColors(colors ...Color) Color {
+	if len(colors) == 0 {
+		return Color{}
+	}
+
+	var r, g, b float64
+	for _, color := range colors {
+		lr, lg, lb := (colorful.Color{R: color.R, G: color.G, B: color.B}).LinearRgb()
+		r += lr
+		g += lg
+		b += lb
+	}
+
+	n := float64(len(colors))
+	cc := colorful.LinearRgb(r/n, g/n, b/n).Clamped()
+
+	return Color{R: cc.R, G: cc.G, B: cc.B}
+}
+
+// Luminance gets the relative luminance of the color, from 0 for black to 1 for white.
+func (c *Color) Luminance() float64 {
+	r, g, b := (colorful.Color{R: c.R, G: c.G, B: c.B}).LinearRgb()
+
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// Contrast gets the WCAG contrast ratio between the colors, from 1 for none to 21 for black on white.
+func (c *Color) Contrast(other Color) float64 {
+	l1 := c.Luminance()
+	l2 := other.Luminance()
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+// A Gradient is a sequence of evenly spaced colors with the colors in between interpolated.
+type Gradient struct {
+	Colors []Color
+
+	// Space is the color space to interpolate in. If empty, it is Oklab.
+	Space ColorSpace
+}
+
+// NewGradient creates a gradient through the colors.
+func NewGradient(colors ...Color) Gradient {
+	return Gradient{Colors: colors}
+}
+
+// At gets the color at t, where 0 is the first color and 1 is the last.
+func (gradient Gradient) At(t float64) Color {
+	switch len(gradient.Colors) {
+	case 0:
+		return Color{}
+	case 1:
+		return gradient.Colors[0]
+	}
+
+	space := gradient.Space
+	if space == "" {
+		space = ColorSpaceOklab
+	}
+
+	if t <= 0 {
+		return gradient.Colors[0]
+	} else if t >= 1 {
+		return gradient.Colors[len(gradient.Colors)-1]
+	}
+
+	position := t * float64(len(gradient.Colors)-1)
+	index := int(position)
+
+	return gradient.Colors[index].LerpIn(space, gradient.Colors[index+1], position-float64(index))
+}
+
+// Sample gets n evenly spaced colors from the gradient, including the first and last color.
+func (gradient Gradient) Sample(n int) []Color {
+	if n <= 0 {
+		return nil
+	} else if n == 1 {
+		return []Color{gradient.At(0)}
+	}
+
+	colors := make([]Color, n)
+	for i := range colors {
+		colors[i] = gradient.At(float64(i) / float64(n-1))
+	}
+
+	return colors
+}
+
+// oklab converts the color to Oklab.
+func oklab(cc colorful.Color) (l, a, b float64) {
+	r, g, bl := cc.LinearRgb()
+
+	lc := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*bl)
+	mc := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*bl)
+	sc := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*bl)
+
+	l = 0.2104542553*lc + 0.7936177850*mc - 0.0040720468*sc
+	a = 1.9779984951*lc - 2.4285922050*mc + 0.4505937099*sc
+	b = 0.0259040371*lc + 0.7827717662*mc - 0.8086757660*sc
+
+	return l, a, b
+}
+
+// fromOklab converts the Oklab color to RGB. The result may be out of range.
+func fromOklab(l, a, b float64) colorful.Color {
+	lc := l + 0.3963377774*a + 0.2158037573*b
+	mc := l - 0.1055613458*a - 0.0638541728*b
+	sc := l - 0.0894841775*a - 1.2914855480*b
+
+	lc, mc, sc = lc*lc*lc, mc*mc*mc, sc*sc*sc
+
+	return colorful.LinearRgb(
+		4.0767416621*lc-3.3077115913*mc+0.2309699292*sc,
+		-1.2684380046*lc+2.6097574011*mc-0.3413193965*sc,
+		-0.0041960863*lc-0.7034186147*mc+1.7076147010*sc,
+	)
+}
@@ -0,0 +1,61 @@
+package lucifer_test
+
+import (
+	"github.com/gissleh/lucifer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestColor_Lerp(t *testing.T) {
+	red := lucifer.MustParseColor("#ff0000")
+	blue := lucifer.MustParseColor("#0000ff")
+
+	assert.Equal(t, red, red.Lerp(blue, 0))
+	assert.Equal(t, blue, red.Lerp(blue, 1))
+
+	for _, space := range []lucifer.ColorSpace{lucifer.ColorSpaceOklab, lucifer.ColorSpaceLab, lucifer.ColorSpaceHCL, lucifer.ColorSpaceRGB} {
+		middle := red.LerpIn(space, blue, 0.5)
+		h, _, _ := middle.HSV()
+		assert.True(t, h > 240 && h < 360, "hue of the middle in %s: %f", space, h)
+	}
+
+	// Whites from color temperatures stay whites.
+	warm := lucifer.MustParseColor("2000k")
+	cold := lucifer.MustParseColor("6000k")
+	assert.Equal(t, lucifer.MustParseColor("4000k"), warm.Lerp(cold, 0.5))
+}
+
+func TestMixColors(t *testing.T) {
+	assert.Equal(t, "#bcbc00", lucifer.MixColors(lucifer.MustParseColor("red"), lucifer.MustParseColor("lime")).String())
+	assert.Equal(t, lucifer.Color{}, lucifer.MixColors())
+}
+
+func TestColor_Contrast(t *testing.T) {
+	black := lucifer.MustParseColor("black")
+	white := lucifer.MustParseColor("white")
+
+	assert.InDelta(t, 0, black.Luminance(), 0.0001)
+	assert.InDelta(t, 1, white.Luminance(), 0.0001)
+	assert.InDelta(t, 21, black.Contrast(white), 0.0001)
+	assert.InDelta(t, 21, white.Contrast(black), 0.0001)
+	assert.InDelta(t, 1, white.Contrast(white), 0.0001)
+}
+
+func TestGradient_Sample(t *testing.T) {
+	gradient := lucifer.NewGradient(
+		lucifer.MustParseColor("#ff0000"),
+		lucifer.MustParseColor("#00ff00"),
+		lucifer.MustParseColor("#0000ff"),
+	)
+
+	colors := gradient.Sample(5)
+	if assert.Len(t, colors, 5) {
+		assert.Equal(t, "#ff0000", colors[0].String())
+		assert.Equal(t, "#00ff00", colors[2].String())
+		assert.Equal(t, "#0000ff", colors[4].String())
+	}
+
+	assert.Empty(t, gradient.Sample(0))
+	assert.Equal(t, []lucifer.Color{gradient.Colors[0]}, gradient.Sample(1))
+	assert.Equal(t, lucifer.Color{}, lucifer.Gradient{}.At(0.5))
+}
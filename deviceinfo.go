@@ -0,0 +1,30 @@
+package lucifer
+
+import "time"
+
+// LowBatteryLevel is the battery level in percent at or below which DeviceInfo.LowBattery is true.
+const LowBatteryLevel = 20
+
+// DeviceInfo describes the hardware behind a light, sensor or bridge, and its health. Fields the
+// driver does not know are left empty.
+type DeviceInfo struct {
+	Manufacturer    string `json:"manufacturer"`
+	ModelID         string `json:"modelId"`
+	ProductName     string `json:"productName"`
+	FirmwareVersion string `json:"firmwareVersion"`
+
+	// Reachable is whether the device responded the last time it was contacted.
+	Reachable bool `json:"reachable"`
+
+	// Battery is the battery level in percent, or nil if the device has no battery.
+	Battery *int `json:"battery"`
+
+	// LastSeen is when the device was last heard from, or zero if it is unknown. Drivers that cannot
+	// tell use when the device's state last changed, which may be long ago for sensors like switches.
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// LowBattery gets whether the device has a battery that needs to be replaced soon.
+func (info DeviceInfo) LowBattery() bool {
+	return info.Battery != nil && *info.Battery <= LowBatteryLevel
+}
@@ -0,0 +1,19 @@
+package lucifer
+
+// A DiscoveredBridge is a bridge found on the local network that has not necessarily been set up.
+type DiscoveredBridge struct {
+	// Driver is the driver kind. It is only set by luciferdrivers.Discover.
+	Driver string `json:"driver,omitempty"`
+
+	// Address is the address to pass to SetupBridge or AddBridge.
+	Address string `json:"address"`
+
+	// ID is the bridge ID as it will be returned by Bridge.ID once added.
+	ID string `json:"id"`
+
+	// Model is the bridge's model.
+	Model string `json:"model"`
+
+	// Name is the bridge's name.
+	Name string `json:"name"`
+}
@@ -11,4 +11,5 @@ type Driver interface {
 	RemoveBridge(ctx context.Context, id string) error
 	Bridge(id string) Bridge
 	Bridges() []Bridge
+	Discover(ctx context.Context) ([]DiscoveredBridge, error)
 }
@@ -0,0 +1,122 @@
+package lucifer
+
+import (
+	"context"
+	"time"
+)
+
+// An Effect is an animation that runs on one or more lights until it is cancelled.
+type Effect interface {
+	// Native is the name of the native effect that lights supporting it run instead, or empty if
+	// there is none.
+	Native() string
+
+	// Interval is the time between frames. Frames are further apart if the rate limit requires it.
+	Interval() time.Duration
+
+	// Frame gets the state of light number index out of count at the time since the effect started.
+	Frame(elapsed time.Duration, index, count int) LightState
+}
+
+// minFrameInterval is the shortest time between frames, for effects without a usable interval.
+const minFrameInterval = time.Millisecond * 10
+
+// RateLimited is implemented by lights whose driver already sends their commands within a rate limit.
+type RateLimited interface {
+	// RateLimiter gets the limiter used by the driver, which may be nil.
+	RateLimiter() *RateLimiter
+}
+
+// RunEffect runs the effect on the lights until the context is done, and then returns its error.
+// Lights with the effect's native effect run that instead, and it is stopped when RunEffect returns.
+// The limiter should be the one shared by everything sending commands to the lights' bridge, or nil.
+// It is not used for lights that are RateLimited, since their driver's own limiter is used instead.
+func RunEffect(ctx context.Context, limiter *RateLimiter, effect Effect, lights ...Light) error {
+	native := effect.Native()
+	animated := make([]int, 0, len(lights))
+
+	// waits are the limiters to wait for before sending commands to each light, which are nil for
+	// the lights whose driver waits by itself.
+	waits := make([]*RateLimiter, len(lights))
+	for i, light := range lights {
+		if _, ok := light.(RateLimited); !ok {
+			waits[i] = limiter
+		}
+	}
+
+	for i, light := range lights {
+		capabilities := light.Capabilities()
+		if native == "" || !capabilities.HasEffect(native) {
+			animated = append(animated, i)
+			continue
+		}
+
+		if err := waits[i].Wait(ctx); err != nil {
+			return err
+		}
+		if err := light.SetEffect(ctx, native); err != nil {
+			return err
+		}
+
+		defer stopEffect(waits[i], light)
+	}
+
+	if len(animated) == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	// Every limiter needs to fit a command to each of its lights in every frame.
+	interval := effect.Interval()
+	perLimiter := make(map[*RateLimiter]time.Duration, 1)
+	for _, index := range animated {
+		lightLimiter := limiter
+		if rateLimited, ok := lights[index].(RateLimited); ok {
+			lightLimiter = rateLimited.RateLimiter()
+		}
+
+		perLimiter[lightLimiter] += lightLimiter.Interval()
+		if perLimiter[lightLimiter] > interval {
+			interval = perLimiter[lightLimiter]
+		}
+	}
+	if interval < minFrameInterval {
+		interval = minFrameInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	started := time.Now()
+	for {
+		elapsed := time.Since(started)
+		for _, index := range animated {
+			if err := waits[index].Wait(ctx); err != nil {
+				return err
+			}
+
+			err := lights[index].SetState(ctx, effect.Frame(elapsed, index, len(lights)))
+			if ctx.Err() != nil {
+				return ctx.Err()
+			} else if err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// stopEffect stops the light's native effect. It gets its own context since the effect's is done.
+func stopEffect(limiter *RateLimiter, light Light) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if limiter.Wait(ctx) == nil {
+		_ = light.SetEffect(ctx, "")
+	}
+}
@@ -0,0 +1,184 @@
+package lucifer
+
+import (
+	"math"
+	"time"
+)
+
+// MinPulsePeriod is the shortest period of Pulse, which keeps it at or below three flashes per second.
+const MinPulsePeriod = time.Second / 3
+
+// MinEffectPeriod is the shortest period of the other effects with a period.
+const MinEffectPeriod = time.Second
+
+// clampPeriod raises the period to the minimum.
+func clampPeriod(period, min time.Duration) time.Duration {
+	if period < min {
+		return min
+	}
+
+	return period
+}
+
+// Breathe slowly dims the lights down and back up over the period. Periods shorter than
+// MinEffectPeriod are raised to it.
+func Breathe(color Color, period time.Duration) Effect {
+	return &breatheEffect{color: color, period: clampPeriod(period, MinEffectPeriod)}
+}
+
+type breatheEffect struct {
+	color  Color
+	period time.Duration
+}
+
+func (effect *breatheEffect) Native() string {
+	return ""
+}
+
+func (effect *breatheEffect) Interval() time.Duration {
+	return effect.period / 16
+}
+
+func (effect *breatheEffect) Frame(elapsed time.Duration, index, count int) LightState {
+	phase := float64(elapsed%effect.period) / float64(effect.period)
+
+	return LightState{
+		Power:      true,
+		Brightness: 0.1 + 0.9*(1+math.Cos(phase*2*math.Pi))/2,
+		Color:      effect.color,
+		Transition: effect.Interval(),
+	}
+}
+
+// CandleFlicker makes the lights flicker independently of each other, like candles.
+func CandleFlicker(color Color) Effect {
+	return &candleEffect{color: color}
+}
+
+type candleEffect struct {
+	color Color
+}
+
+func (effect *candleEffect) Native() string {
+	return ""
+}
+
+func (effect *candleEffect) Interval() time.Duration {
+	return time.Millisecond * 150
+}
+
+func (effect *candleEffect) Frame(elapsed time.Duration, index, count int) LightState {
+	step := uint64(elapsed / effect.Interval())
+
+	return LightState{
+		Power:      true,
+		Brightness: 0.55 + 0.45*noise(step, index),
+		Color:      effect.color,
+		Transition: effect.Interval(),
+	}
+}
+
+// ColorLoop cycles the lights through all hues over the period. Lights with the native colorloop
+// effect run that instead, at the speed the light decides. Periods shorter than MinEffectPeriod are
+// raised to it.
+func ColorLoop(brightness float64, period time.Duration) Effect {
+	return &colorLoopEffect{brightness: brightness, period: clampPeriod(period, MinEffectPeriod)}
+}
+
+type colorLoopEffect struct {
+	brightness float64
+	period     time.Duration
+}
+
+func (effect *colorLoopEffect) Native() string {
+	return "colorloop"
+}
+
+func (effect *colorLoopEffect) Interval() time.Duration {
+	return effect.period / 36
+}
+
+func (effect *colorLoopEffect) Frame(elapsed time.Duration, index, count int) LightState {
+	state := LightState{Power: true, Brightness: effect.brightness, Transition: effect.Interval()}
+	state.Color.SetHSV(360*float64(elapsed%effect.period)/float64(effect.period), 1, 1)
+
+	return state
+}
+
+// Pulse fades the lights between full and low brightness, with a full pulse every period. Periods
+// shorter than MinPulsePeriod are raised to it.
+func Pulse(color Color, period time.Duration) Effect {
+	return &pulseEffect{color: color, period: clampPeriod(period, MinPulsePeriod)}
+}
+
+type pulseEffect struct {
+	color  Color
+	period time.Duration
+}
+
+func (effect *pulseEffect) Native() string {
+	return ""
+}
+
+func (effect *pulseEffect) Interval() time.Duration {
+	return effect.period / 2
+}
+
+func (effect *pulseEffect) Frame(elapsed time.Duration, index, count int) LightState {
+	brightness := 1.0
+	if (elapsed/effect.Interval())%2 == 1 {
+		brightness = 0.1
+	}
+
+	return LightState{
+		Power:      true,
+		Brightness: brightness,
+		Color:      effect.color,
+		Transition: effect.Interval(),
+	}
+}
+
+// GradientCycle spreads the gradient across the lights, and moves it back and forth over the period.
+// Periods shorter than MinEffectPeriod are raised to it.
+func GradientCycle(gradient Gradient, brightness float64, period time.Duration) Effect {
+	return &gradientCycleEffect{gradient: gradient, brightness: brightness, period: clampPeriod(period, MinEffectPeriod)}
+}
+
+type gradientCycleEffect struct {
+	gradient   Gradient
+	brightness float64
+	period     time.Duration
+}
+
+func (effect *gradientCycleEffect) Native() string {
+	return ""
+}
+
+func (effect *gradientCycleEffect) Interval() time.Duration {
+	return effect.period / 32
+}
+
+func (effect *gradientCycleEffect) Frame(elapsed time.Duration, index, count int) LightState {
+	position := float64(elapsed%effect.period)/float64(effect.period) + float64(index)/float64(count)
+	position -= math.Floor(position)
+
+	return LightState{
+		Power:      true,
+		Brightness: effect.brightness,
+		Color:      effect.gradient.At(1 - math.Abs(2*position-1)),
+		Transition: effect.Interval(),
+	}
+}
+
+// noise gets a pseudo-random number from 0 to 1 for the step and index. The same arguments always
+// give the same number, so that effects can be shared between goroutines.
+func noise(step uint64, index int) float64 {
+	x := step*0x9e3779b97f4a7c15 + uint64(index)*0xbf58476d1ce4e5b9
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return float64(x>>11) / float64(1<<53)
+}
@@ -0,0 +1,142 @@
+package lucifer_test
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+	"github.com/gissleh/lucifer/luciferdrivers/virtual"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreathe(t *testing.T) {
+	effect := lucifer.Breathe(lucifer.MustParseColor("#ff8000"), time.Second*4)
+
+	assert.Equal(t, "", effect.Native())
+	assert.InDelta(t, 1, effect.Frame(0, 0, 1).Brightness, 0.001)
+	assert.InDelta(t, 0.1, effect.Frame(time.Second*2, 0, 1).Brightness, 0.001)
+	assert.InDelta(t, 1, effect.Frame(time.Second*4, 0, 1).Brightness, 0.001)
+	assert.Equal(t, lucifer.MustParseColor("#ff8000"), effect.Frame(time.Second, 0, 1).Color)
+}
+
+func TestCandleFlicker(t *testing.T) {
+	effect := lucifer.CandleFlicker(lucifer.MustParseColor("candlelight"))
+
+	different := false
+	for i := 0; i < 50; i++ {
+		elapsed := effect.Interval() * time.Duration(i)
+		a := effect.Frame(elapsed, 0, 2)
+		b := effect.Frame(elapsed, 1, 2)
+
+		assert.True(t, a.Brightness >= 0.55 && a.Brightness <= 1, "brightness %f", a.Brightness)
+		assert.Equal(t, a, effect.Frame(elapsed, 0, 2), "same frame twice")
+		if a.Brightness != b.Brightness {
+			different = true
+		}
+	}
+
+	assert.True(t, different, "lights flicker independently")
+}
+
+func TestColorLoop(t *testing.T) {
+	effect := lucifer.ColorLoop(0.8, time.Second*36)
+
+	assert.Equal(t, "colorloop", effect.Native())
+	assert.Equal(t, time.Second, effect.Interval())
+
+	color := effect.Frame(time.Second*9, 0, 1).Color
+	h, _, _ := color.HSV()
+	assert.InDelta(t, 90, h, 0.5)
+	assert.Equal(t, 0.8, effect.Frame(0, 0, 1).Brightness)
+}
+
+func TestPulse(t *testing.T) {
+	effect := lucifer.Pulse(lucifer.MustParseColor("#ffffff"), time.Millisecond*50)
+
+	assert.Equal(t, lucifer.MinPulsePeriod/2, effect.Interval(), "period raised to the minimum")
+	assert.Equal(t, 1.0, effect.Frame(0, 0, 1).Brightness)
+	assert.Equal(t, 0.1, effect.Frame(lucifer.MinPulsePeriod/2, 0, 1).Brightness)
+	assert.Equal(t, 1.0, effect.Frame(lucifer.MinPulsePeriod, 0, 1).Brightness)
+}
+
+func TestGradientCycle(t *testing.T) {
+	red := lucifer.MustParseColor("#ff0000")
+	blue := lucifer.MustParseColor("#0000ff")
+	effect := lucifer.GradientCycle(lucifer.NewGradient(red, blue), 1, time.Second*4)
+
+	assert.Equal(t, red, effect.Frame(0, 0, 2).Color)
+	assert.Equal(t, blue, effect.Frame(0, 1, 2).Color)
+	assert.Equal(t, blue, effect.Frame(time.Second*2, 0, 2).Color)
+	assert.Equal(t, red, effect.Frame(time.Second*2, 1, 2).Color)
+}
+
+func TestEffects_ZeroPeriod(t *testing.T) {
+	gradient := lucifer.NewGradient(lucifer.MustParseColor("#ff0000"), lucifer.MustParseColor("#0000ff"))
+	effects := []lucifer.Effect{
+		lucifer.Breathe(lucifer.MustParseColor("#ffffff"), 0),
+		lucifer.ColorLoop(1, 0),
+		lucifer.Pulse(lucifer.MustParseColor("#ffffff"), 0),
+		lucifer.GradientCycle(gradient, 1, time.Nanosecond),
+	}
+
+	for _, effect := range effects {
+		assert.True(t, effect.Interval() > 0, "%T", effect)
+		assert.NotPanics(t, func() { effect.Frame(time.Second, 0, 1) }, "%T", effect)
+	}
+}
+
+// countingLight counts the states that are set.
+type countingLight struct {
+	lucifer.Light
+	count int32
+}
+
+func (light *countingLight) SetState(ctx context.Context, state lucifer.LightState) error {
+	atomic.AddInt32(&light.count, 1)
+	return light.Light.SetState(ctx, state)
+}
+
+// unlimitedLight is a light whose driver has no rate limit.
+type unlimitedLight struct {
+	countingLight
+}
+
+func (light *unlimitedLight) RateLimiter() *lucifer.RateLimiter {
+	return nil
+}
+
+func TestRunEffect_MixedLimiters(t *testing.T) {
+	bridge := virtual.New().SimulateBridge("10.0.0.2", "bridge-1", "Test Bridge")
+	limited := &countingLight{Light: bridge.AddLight("a", "A", virtual.DimmableCapabilities)}
+	unlimited := &unlimitedLight{countingLight{Light: bridge.AddLight("b", "B", virtual.DimmableCapabilities)}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer cancel()
+
+	err := lucifer.RunEffect(ctx, lucifer.NewRateLimiter(4), lucifer.Breathe(lucifer.MustParseColor("#ffffff"), time.Second), limited, unlimited)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// Without the limit, the effect would send a frame every 62.5ms.
+	assert.True(t, atomic.LoadInt32(&limited.count) <= 3, "%d states sent within the limit", limited.count)
+	assert.InDelta(t, atomic.LoadInt32(&limited.count), atomic.LoadInt32(&unlimited.count), 1, "the frame may be cut short")
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := lucifer.NewRateLimiter(20)
+	assert.Equal(t, time.Millisecond*50, limiter.Interval())
+
+	started := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, limiter.Wait(context.Background()))
+	}
+	assert.True(t, time.Since(started) >= time.Millisecond*200, "five commands took %s", time.Since(started))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, limiter.Wait(ctx))
+
+	var none *lucifer.RateLimiter
+	assert.NoError(t, none.Wait(context.Background()))
+}
@@ -1,6 +1,9 @@
 package lucifer
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrUnsupportedOperation = errors.New("lucifer: operation not supported for this driver")
 
@@ -9,3 +12,58 @@ var ErrUnsupportedDriver = errors.New("lucifer: driver not supported")
 
 // ErrBridgeNotFound is returned if a bridge cannot be found and some operations fails because of it.
 var ErrBridgeNotFound = errors.New("lucifer: bridge not found")
+
+// ErrLightNotFound is returned if a light cannot be found on the bridge.
+var ErrLightNotFound = errors.New("lucifer: light not found")
+
+// ErrSensorNotFound is returned if a sensor cannot be found on the bridge.
+var ErrSensorNotFound = errors.New("lucifer: sensor not found")
+
+// ErrGroupNotFound is returned if a group cannot be found on the bridge.
+var ErrGroupNotFound = errors.New("lucifer: group not found")
+
+// ErrSceneNotFound is returned if a scene cannot be found on the bridge.
+var ErrSceneNotFound = errors.New("lucifer: scene not found")
+
+// ErrUnauthorized is returned if the bridge does not accept the key.
+var ErrUnauthorized = errors.New("lucifer: unauthorized")
+
+// ErrBridgeUnreachable is returned if the bridge cannot be reached.
+var ErrBridgeUnreachable = errors.New("lucifer: bridge unreachable")
+
+// ErrLinkButtonNotPressed is returned if setting up a bridge requires its link button to be pressed.
+var ErrLinkButtonNotPressed = errors.New("lucifer: link button not pressed")
+
+// ErrRateLimited is returned if the bridge refuses a request because too many were sent.
+var ErrRateLimited = errors.New("lucifer: rate limited")
+
+// DriverError is an error reported by a bridge. Err is the matching error from this package, if
+// there is one, so that errors.Is can be used on it.
+type DriverError struct {
+	// Driver is the name of the driver.
+	Driver string
+
+	// Type is the bridge's error type.
+	Type int
+
+	// Address is the resource the error is about.
+	Address string
+
+	// Description is the bridge's description of the error.
+	Description string
+
+	Err error
+}
+
+func (err *DriverError) Error() string {
+	if err.Address != "" {
+		return fmt.Sprintf("%s: error type %d at %s: %s", err.Driver, err.Type, err.Address, err.Description)
+	}
+
+	return fmt.Sprintf("%s: error type %d: %s", err.Driver, err.Type, err.Description)
+}
+
+// Unwrap gets Err.
+func (err *DriverError) Unwrap() error {
+	return err.Err
+}
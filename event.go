@@ -0,0 +1,36 @@
+package lucifer
+
+import "time"
+
+// EventKind is the kind of an event.
+type EventKind string
+
+const (
+	// EventLightStateChanged is sent when a light's state has changed.
+	EventLightStateChanged EventKind = "LightStateChanged"
+	// EventLightAdded is sent when a light has been added to the bridge.
+	EventLightAdded EventKind = "LightAdded"
+	// EventLightRemoved is sent when a light has been removed from the bridge.
+	EventLightRemoved EventKind = "LightRemoved"
+	// EventSensorStateChanged is sent when a sensor's state has changed.
+	EventSensorStateChanged EventKind = "SensorStateChanged"
+	// EventReachabilityChanged is sent when the bridge has lost or regained contact with a device.
+	EventReachabilityChanged EventKind = "ReachabilityChanged"
+	// EventBridgeDisconnected is sent when the bridge cannot be reached.
+	EventBridgeDisconnected EventKind = "BridgeDisconnected"
+)
+
+// An Event is a change on a bridge. Which fields are set depend on the kind.
+type Event struct {
+	Kind        EventKind    `json:"kind"`
+	Time        time.Time    `json:"time"`
+	BridgeID    string       `json:"bridgeId"`
+	LightID     string       `json:"lightId,omitempty"`
+	SensorID    string       `json:"sensorId,omitempty"`
+	LightState  *LightState  `json:"lightState,omitempty"`
+	SensorState *SensorState `json:"sensorState,omitempty"`
+	Reachable   *bool        `json:"reachable,omitempty"`
+
+	// Err is the reason for a disconnect.
+	Err error `json:"-"`
+}
@@ -0,0 +1,68 @@
+package lucifer
+
+import "math"
+
+// gamutTriangles are the red, green and blue corners of the gamuts in CIE 1931 xy coordinates.
+var gamutTriangles = map[ColorGamut][3][2]float64{
+	GamutA: {{0.704, 0.296}, {0.2151, 0.7106}, {0.138, 0.08}},
+	GamutB: {{0.675, 0.322}, {0.409, 0.518}, {0.167, 0.04}},
+	GamutC: {{0.6915, 0.3083}, {0.17, 0.7}, {0.1532, 0.0475}},
+}
+
+// ContainsXY returns true if the CIE 1931 xy coordinates are inside the gamut. Gamuts without a known
+// triangle contain every point.
+func (gamut ColorGamut) ContainsXY(x, y float64) bool {
+	triangle, ok := gamutTriangles[gamut]
+	if !ok {
+		return true
+	}
+
+	// The point is inside if it is on the same side of all three edges.
+	d1 := edgeSide(x, y, triangle[0], triangle[1])
+	d2 := edgeSide(x, y, triangle[1], triangle[2])
+	d3 := edgeSide(x, y, triangle[2], triangle[0])
+	hasNegative := d1 < 0 || d2 < 0 || d3 < 0
+	hasPositive := d1 > 0 || d2 > 0 || d3 > 0
+
+	return !(hasNegative && hasPositive)
+}
+
+// ClampXY gets the closest CIE 1931 xy coordinates inside the gamut.
+func (gamut ColorGamut) ClampXY(x, y float64) (float64, float64) {
+	if gamut.ContainsXY(x, y) {
+		return x, y
+	}
+
+	triangle := gamutTriangles[gamut]
+	bestX, bestY := x, y
+	bestDistance := math.Inf(1)
+	for i := range triangle {
+		cx, cy := closestOnEdge(x, y, triangle[i], triangle[(i+1)%3])
+		distance := math.Hypot(x-cx, y-cy)
+		if distance < bestDistance {
+			bestX, bestY = cx, cy
+			bestDistance = distance
+		}
+	}
+
+	return bestX, bestY
+}
+
+// edgeSide gets which side of the line from a to b that the point is on.
+func edgeSide(x, y float64, a, b [2]float64) float64 {
+	return (x-b[0])*(a[1]-b[1]) - (a[0]-b[0])*(y-b[1])
+}
+
+// closestOnEdge gets the closest point on the line segment from a to b.
+func closestOnEdge(x, y float64, a, b [2]float64) (float64, float64) {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+
+	t := ((x-a[0])*dx + (y-a[1])*dy) / (dx*dx + dy*dy)
+	if t < 0 {
+		t = 0
+	} else if t > 1 {
+		t = 1
+	}
+
+	return a[0] + dx*t, a[1] + dy*t
+}
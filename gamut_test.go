@@ -0,0 +1,29 @@
+package lucifer_test
+
+import (
+	"github.com/gissleh/lucifer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestColorGamut_ClampXY(t *testing.T) {
+	table := []struct {
+		gamut  lucifer.ColorGamut
+		x, y   float64
+		cx, cy float64
+	}{
+		{gamut: lucifer.GamutC, x: 0.3127, y: 0.329, cx: 0.3127, cy: 0.329},
+		{gamut: lucifer.GamutC, x: 0.8, y: 0.2, cx: 0.6915, cy: 0.3083},
+		{gamut: lucifer.GamutB, x: 0.3, y: 0.6, cx: 0.409, cy: 0.518},
+		{gamut: lucifer.GamutB, x: 0.5, y: 0.5, cx: 0.4766, cy: 0.4682},
+		{gamut: lucifer.GamutA, x: 0.1, y: 0.8, cx: 0.2151, cy: 0.7106},
+		{gamut: lucifer.GamutOther, x: 0.1, y: 0.8, cx: 0.1, cy: 0.8},
+	}
+
+	for _, row := range table {
+		x, y := row.gamut.ClampXY(row.x, row.y)
+		assert.InDelta(t, row.cx, x, 0.001, "x of %v in gamut %s", row, row.gamut)
+		assert.InDelta(t, row.cy, y, 0.001, "y of %v in gamut %s", row, row.gamut)
+		assert.Equal(t, row.x == row.cx && row.y == row.cy, row.gamut.ContainsXY(row.x, row.y), "%v in gamut %s", row, row.gamut)
+	}
+}
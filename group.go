@@ -0,0 +1,34 @@
+package lucifer
+
+import "context"
+
+// GroupKind is the kind of a group.
+type GroupKind string
+
+const (
+	// GroupKindRoom is a group of lights in the same room. A light can only be in one room.
+	GroupKindRoom GroupKind = "Room"
+	// GroupKindZone is an arbitrary area that may overlap with rooms and other zones.
+	GroupKindZone GroupKind = "Zone"
+	// GroupKindLightGroup is a plain collection of lights.
+	GroupKindLightGroup GroupKind = "LightGroup"
+	// GroupKindOther is used for groups managed by the driver, like a luminaire's lights.
+	GroupKindOther GroupKind = "Other"
+)
+
+type Group interface {
+	// ID gets the group's ID.
+	ID() string
+
+	// Name gets the group's name.
+	Name() string
+
+	// Kind gets what kind of group it is.
+	Kind() GroupKind
+
+	// Lights gets the lights in the group.
+	Lights(ctx context.Context) ([]Light, error)
+
+	// SetState sets the state of all lights in the group at once.
+	SetState(ctx context.Context, state LightState) error
+}
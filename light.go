@@ -1,5 +1,7 @@
 package lucifer
 
+import "context"
+
 type Light interface {
 	// ID gets the light's ID.
 	ID() string
@@ -8,14 +10,30 @@ type Light interface {
 	Name() string
 
 	// SetName sets the light's name
-	SetName(name string) error
+	SetName(ctx context.Context, name string) error
+
+	// DeviceInfo describes the light's hardware as of when its state was last fetched.
+	DeviceInfo() DeviceInfo
+
+	// Capabilities describes what the light can do.
+	Capabilities() LightCapabilities
+
+	// Refresh fetches the light's state, for drivers that cache it.
+	Refresh(ctx context.Context) error
 
 	// State is the light's state.
-	State() (LightState, error)
+	State(ctx context.Context) (LightState, error)
 
 	// SetState syncs the state.
-	SetState(state LightState) error
+	SetState(ctx context.Context, state LightState) error
+
+	// Update changes only the parts of the state that are set in the update.
+	Update(ctx context.Context, update LightUpdate) error
+
+	// SetEffect starts one of the native effects in the capabilities, or stops it if the name is
+	// empty. Changing the state also stops it.
+	SetEffect(ctx context.Context, name string) error
 
 	// Forget forgets the light.
-	Forget() error
+	Forget(ctx context.Context) error
 }
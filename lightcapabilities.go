@@ -0,0 +1,72 @@
+package lucifer
+
+// ColorGamut identifies the range of colors a light can reproduce.
+type ColorGamut string
+
+const (
+	// GamutNone is used for lights that cannot display colors.
+	GamutNone ColorGamut = ""
+	// GamutA is the gamut of the first generation LivingColors lights.
+	GamutA ColorGamut = "A"
+	// GamutB is the gamut of the first generation Hue bulbs.
+	GamutB ColorGamut = "B"
+	// GamutC is the wider gamut of the later Hue bulbs and strips.
+	GamutC ColorGamut = "C"
+	// GamutOther is used for color lights where the gamut is unknown.
+	GamutOther ColorGamut = "other"
+)
+
+// LightCapabilities describes what a light is able to do.
+type LightCapabilities struct {
+	// Color is whether the light can display arbitrary colors.
+	Color bool `json:"color"`
+
+	// ColorTemperature is whether the light can display shades of white.
+	ColorTemperature bool `json:"colorTemperature"`
+
+	// MinKelvin is the warmest color temperature supported, or 0 if not supported.
+	MinKelvin int `json:"minKelvin"`
+
+	// MaxKelvin is the coldest color temperature supported, or 0 if not supported.
+	MaxKelvin int `json:"maxKelvin"`
+
+	// Gamut is the color gamut of the light.
+	Gamut ColorGamut `json:"gamut"`
+
+	// BrightnessSteps is the number of distinct brightness levels, or 0 if the light cannot be dimmed.
+	BrightnessSteps int `json:"brightnessSteps"`
+
+	// Effects is a list of the native effects the light supports.
+	Effects []string `json:"effects"`
+
+	// Transition is whether the light can natively fade between states.
+	Transition bool `json:"transition"`
+}
+
+// Dimmable gets whether the brightness can be changed.
+func (capabilities *LightCapabilities) Dimmable() bool {
+	return capabilities.BrightnessSteps > 0
+}
+
+// HasEffect gets whether the named native effect is supported.
+func (capabilities *LightCapabilities) HasEffect(name string) bool {
+	for _, effect := range capabilities.Effects {
+		if effect == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClampKelvin limits the color temperature to the supported range.
+func (capabilities *LightCapabilities) ClampKelvin(kelvin int) int {
+	if capabilities.MinKelvin > 0 && kelvin < capabilities.MinKelvin {
+		return capabilities.MinKelvin
+	}
+	if capabilities.MaxKelvin > 0 && kelvin > capabilities.MaxKelvin {
+		return capabilities.MaxKelvin
+	}
+
+	return kelvin
+}
@@ -1,7 +1,13 @@
 package lucifer
 
+import "time"
+
 type LightState struct {
 	Color      Color
 	Brightness float64
 	Power      bool
+
+	// Transition is how long the change should take. If zero, the driver's default is used. Drivers
+	// for lights that cannot fade natively ignore it, and Fade should be used instead.
+	Transition time.Duration
 }
@@ -0,0 +1,63 @@
+package lucifer
+
+import "time"
+
+// LightUpdate is a partial change to a light's state. Nil fields are left as they are, so that
+// changes made by other controllers in the meantime are not overwritten.
+type LightUpdate struct {
+	Power      *bool
+	Brightness *float64
+
+	// Color changes the color. A color with K set changes the color temperature.
+	Color *Color
+
+	// Kelvin changes the color temperature. It takes precedence over Color.
+	Kelvin *int
+
+	// Transition is how long the change should take. If zero, the driver's default is used.
+	Transition time.Duration
+}
+
+// SetPower sets the power in the update.
+func (update *LightUpdate) SetPower(power bool) {
+	update.Power = &power
+}
+
+// SetBrightness sets the brightness in the update.
+func (update *LightUpdate) SetBrightness(brightness float64) {
+	update.Brightness = &brightness
+}
+
+// SetColor sets the color in the update, and clears the kelvin.
+func (update *LightUpdate) SetColor(color Color) {
+	update.Color = &color
+	update.Kelvin = nil
+}
+
+// SetKelvin sets the color temperature in the update.
+func (update *LightUpdate) SetKelvin(kelvin int) {
+	update.Kelvin = &kelvin
+}
+
+// Empty returns true if the update does not change anything.
+func (update LightUpdate) Empty() bool {
+	return update.Power == nil && update.Brightness == nil && update.Color == nil && update.Kelvin == nil
+}
+
+// Apply gets the state with the update applied.
+func (update LightUpdate) Apply(state LightState) LightState {
+	if update.Power != nil {
+		state.Power = *update.Power
+	}
+	if update.Brightness != nil {
+		state.Brightness = *update.Brightness
+	}
+	if update.Kelvin != nil {
+		state.Color.SetKelvin(*update.Kelvin)
+	} else if update.Color != nil {
+		state.Color = *update.Color
+	}
+	state.Transition = update.Transition
+
+	return state
+}
@@ -0,0 +1,36 @@
+package lucifer_test
+
+import (
+	"github.com/gissleh/lucifer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLightUpdate_Apply(t *testing.T) {
+	state := lucifer.LightState{Power: true, Brightness: 0.5, Color: lucifer.MustParseColor("#ff0000")}
+
+	update := lucifer.LightUpdate{}
+	assert.True(t, update.Empty())
+	assert.Equal(t, state, update.Apply(state))
+
+	update.SetBrightness(0.3)
+	update.Transition = time.Second
+	assert.False(t, update.Empty())
+	assert.Equal(t, lucifer.LightState{
+		Power:      true,
+		Brightness: 0.3,
+		Color:      lucifer.MustParseColor("#ff0000"),
+		Transition: time.Second,
+	}, update.Apply(state))
+
+	update = lucifer.LightUpdate{}
+	update.SetColor(lucifer.MustParseColor("#00ff00"))
+	update.SetKelvin(2700)
+	update.SetPower(false)
+	assert.Equal(t, lucifer.LightState{
+		Power:      false,
+		Brightness: 0.5,
+		Color:      lucifer.MustParseColor("2700k"),
+	}, update.Apply(state))
+}
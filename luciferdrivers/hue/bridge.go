@@ -2,14 +2,36 @@ package hue
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
 	hue "github.com/collinux/gohue"
 	"github.com/gissleh/lucifer"
+	"strconv"
+	"sync"
+	"time"
 )
 
 type bridge struct {
-	gh *hue.Bridge
+	gh       *hue.Bridge
+	config   bridgeConfig
+	events   *eventStream
+	queue    *lucifer.WriteQueue
+	stateTTL time.Duration
+
+	mutex     sync.Mutex
+	reachable bool
+	lastSeen  time.Time
+}
+
+func newBridge(ip, key string, info hue.BridgeInfo, config bridgeConfig) *bridge {
+	return &bridge{
+		gh:        &hue.Bridge{IPAddress: ip, Username: key, Info: info},
+		config:    config,
+		events:    newEventStream(ip, key),
+		queue:     lucifer.NewWriteQueue(nil),
+		reachable: true,
+		lastSeen:  time.Now(),
+	}
 }
 
 func (bridge *bridge) ID() string {
@@ -20,6 +42,21 @@ func (bridge *bridge) Name() string {
 	return bridge.gh.Info.Device.FriendlyName
 }
 
+// DeviceInfo describes the bridge. It is reachable unless the last request failed to reach it.
+func (bridge *bridge) DeviceInfo() lucifer.DeviceInfo {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	return lucifer.DeviceInfo{
+		Manufacturer:    bridge.gh.Info.Device.Manufacturer,
+		ModelID:         bridge.config.ModelID,
+		ProductName:     bridge.gh.Info.Device.ModelName,
+		FirmwareVersion: bridge.config.SWVersion,
+		Reachable:       bridge.reachable,
+		LastSeen:        bridge.lastSeen,
+	}
+}
+
 func (bridge *bridge) Light(ctx context.Context, id string) (lucifer.Light, error) {
 	lights, err := bridge.Lights(ctx)
 	if err != nil {
@@ -32,95 +69,253 @@ func (bridge *bridge) Light(ctx context.Context, id string) (lucifer.Light, erro
 		}
 	}
 
-	return nil, errors.New("light not found")
+	return nil, lucifer.ErrLightNotFound
 }
 
 func (bridge *bridge) Lights(ctx context.Context) ([]lucifer.Light, error) {
-	ghLights, err := bridge.gh.GetAllLights()
+	ghLights, err := bridge.lightsData(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	lights := make([]lucifer.Light, len(ghLights))
 	for i, ghLight := range ghLights {
-		lights[i] = &light{gh: ghLight}
+		lights[i] = newLight(bridge, ghLight)
 	}
 
 	return lights, nil
 }
 
+// DiscoverLights searches for new lights, and returns the ones found once the search is over.
 func (bridge *bridge) DiscoverLights(ctx context.Context) ([]lucifer.Light, error) {
-	before, err := bridge.Lights(ctx)
+	lights, _, err := bridge.discover(ctx, lucifer.DeviceSearch{Lights: true})
+	return lights, err
+}
+
+func (bridge *bridge) Sensor(ctx context.Context, id string) (lucifer.Sensor, error) {
+	sensors, err := bridge.Sensors(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	err = bridge.gh.FindNewLights()
+	for _, sensor := range sensors {
+		if sensor.ID() == id {
+			return sensor, nil
+		}
+	}
+
+	return nil, lucifer.ErrSensorNotFound
+}
+
+func (bridge *bridge) Sensors(ctx context.Context) ([]lucifer.Sensor, error) {
+	ghSensors, err := bridge.sensorsData(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	after, err := bridge.Lights(ctx)
+	sensors := make([]lucifer.Sensor, 0, len(ghSensors))
+	for _, ghSensor := range ghSensors {
+		if ghSensor.UniqueID == "" {
+			continue
+		}
+
+		sensors = append(sensors, newSensor(bridge, ghSensor))
+	}
+
+	return sensors, nil
+}
+
+// DiscoverSensors searches for new sensors, and returns the ones found once the search is over.
+func (bridge *bridge) DiscoverSensors(ctx context.Context) ([]lucifer.Sensor, error) {
+	_, sensors, err := bridge.discover(ctx, lucifer.DeviceSearch{Sensors: true})
+	return sensors, err
+}
+
+func (bridge *bridge) Group(ctx context.Context, id string) (lucifer.Group, error) {
+	data := groupData{}
+	_, err := bridge.request(ctx, "GET", "/groups/"+id, nil, &data)
 	if err != nil {
 		return nil, err
 	}
 
-	newLights := make([]lucifer.Light, 0, 8)
-	for _, alight := range after {
-		found := false
-		for _, blight := range before {
-			if alight.ID() == blight.ID() {
-				found = true
-			}
-		}
+	return &group{bridge: bridge, id: id, data: data}, nil
+}
 
-		if !found {
-			newLights = append(newLights, alight)
-		}
+func (bridge *bridge) Groups(ctx context.Context) ([]lucifer.Group, error) {
+	dataMap := make(map[string]groupData)
+	_, err := bridge.request(ctx, "GET", "/groups", nil, &dataMap)
+	if err != nil {
+		return nil, err
 	}
 
-	return newLights, nil
+	groups := make([]lucifer.Group, 0, len(dataMap))
+	for id, data := range dataMap {
+		groups = append(groups, &group{bridge: bridge, id: id, data: data})
+	}
+
+	return groups, nil
 }
 
-func (bridge *bridge) Sensor(ctx context.Context, id string) (lucifer.Sensor, error) {
-	sensors, err := bridge.Sensors(ctx)
+func (bridge *bridge) CreateGroup(ctx context.Context, name string, kind lucifer.GroupKind, lights []lucifer.Light) (lucifer.Group, error) {
+	data := groupData{
+		Name:   name,
+		Type:   string(kind),
+		Lights: make([]string, 0, len(lights)),
+	}
+	if kind == lucifer.GroupKindOther {
+		data.Type = "LightGroup"
+	}
+
+	for _, l := range lights {
+		hueLight, ok := l.(*light)
+		if !ok {
+			return nil, lucifer.ErrUnsupportedOperation
+		}
+
+		data.Lights = append(data.Lights, strconv.Itoa(hueLight.index))
+	}
+
+	body, err := bridge.write(ctx, "POST", "/groups", data)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, sensor := range sensors {
-		if sensor.ID() == id {
-			return sensor, nil
+	id, err := createdID(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &group{bridge: bridge, id: id, data: data}, nil
+}
+
+func (bridge *bridge) DeleteGroup(ctx context.Context, id string) error {
+	_, err := bridge.write(ctx, "DELETE", "/groups/"+id, nil)
+	return err
+}
+
+func (bridge *bridge) Scene(ctx context.Context, id string) (lucifer.Scene, error) {
+	data := sceneData{}
+	_, err := bridge.request(ctx, "GET", "/scenes/"+id, nil, &data)
+	if err != nil {
+		return lucifer.Scene{}, err
+	}
+
+	ghLights, err := bridge.lightsData(ctx)
+	if err != nil {
+		return lucifer.Scene{}, err
+	}
+
+	scene := lucifer.Scene{
+		ID:     id,
+		Name:   data.Name,
+		States: make(map[string]lucifer.LightState, len(data.LightStates)),
+	}
+	for _, ghLight := range ghLights {
+		if lightData, ok := data.LightStates[strconv.Itoa(ghLight.Index)]; ok {
+			scene.States[ghLight.UniqueID] = lightData.lightState()
 		}
 	}
 
-	return nil, errors.New("sensor not found")
+	return scene, nil
 }
 
-func (bridge *bridge) Sensors(ctx context.Context) ([]lucifer.Sensor, error) {
-	ghSensors, err := bridge.gh.GetAllSensors()
+func (bridge *bridge) Scenes(ctx context.Context) ([]lucifer.Scene, error) {
+	dataMap := make(map[string]sceneData)
+	_, err := bridge.request(ctx, "GET", "/scenes", nil, &dataMap)
 	if err != nil {
 		return nil, err
 	}
 
-	sensors := make([]lucifer.Sensor, 0, len(ghSensors))
-	for _, ghSensor := range ghSensors {
-		if ghSensor.UniqueID == "" {
-			continue
+	scenes := make([]lucifer.Scene, 0, len(dataMap))
+	for id, data := range dataMap {
+		scenes = append(scenes, lucifer.Scene{ID: id, Name: data.Name})
+	}
+
+	return scenes, nil
+}
+
+func (bridge *bridge) CreateScene(ctx context.Context, name string, lights []lucifer.Light) (lucifer.Scene, error) {
+	scene, err := lucifer.CaptureScene(ctx, name, lights)
+	if err != nil {
+		return lucifer.Scene{}, err
+	}
+
+	// The bridge stores the lights' current states when the scene is created.
+	data := sceneData{Name: name, Lights: make([]string, 0, len(lights))}
+	for _, l := range lights {
+		hueLight, ok := l.(*light)
+		if !ok {
+			return lucifer.Scene{}, lucifer.ErrUnsupportedOperation
 		}
 
-		sensors = append(sensors, &sensor{gh: ghSensor})
+		data.Lights = append(data.Lights, strconv.Itoa(hueLight.index))
 	}
 
-	return sensors, nil
+	body, err := bridge.write(ctx, "POST", "/scenes", data)
+	if err != nil {
+		return lucifer.Scene{}, err
+	}
+
+	scene.ID, err = createdID(body)
+	if err != nil {
+		return lucifer.Scene{}, err
+	}
+
+	return scene, nil
 }
 
-func (bridge *bridge) DiscoverSensors(ctx context.Context) ([]lucifer.Sensor, error) {
-	uri := fmt.Sprintf("/api/%s/sensors", bridge.gh.Username)
-	_, _, err := bridge.gh.Post(uri, nil)
+func (bridge *bridge) RecallScene(ctx context.Context, id string) error {
+	_, err := bridge.write(ctx, "PUT", "/groups/0/action", map[string]string{"scene": id})
+	return err
+}
+
+func (bridge *bridge) DeleteScene(ctx context.Context, id string) error {
+	_, err := bridge.write(ctx, "DELETE", "/scenes/"+id, nil)
+	return err
+}
+
+// write sends a request that changes something through the write queue, and returns the response
+// body.
+func (bridge *bridge) write(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	var result []byte
+	err := bridge.queue.Do(ctx, "", func(ctx context.Context) error {
+		var err error
+		result, err = bridge.request(ctx, method, path, body, nil)
+		return err
+	})
+
+	return result, err
+}
+
+// QueueLen gets the number of writes waiting in the bridge's write queue.
+func (bridge *bridge) QueueLen() int {
+	return bridge.queue.Len()
+}
+
+// createdID gets the ID from the bridge's response to a POST creating a resource.
+func createdID(body []byte) (string, error) {
+	var result []struct {
+		Success struct {
+			ID string `json:"id"`
+		} `json:"success"`
+	}
+
+	err := json.Unmarshal(body, &result)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	if len(result) == 0 || result[0].Success.ID == "" {
+		return "", fmt.Errorf("unexpected response from bridge: %s", body)
 	}
 
-	return nil, nil
+	return result[0].Success.ID, nil
+}
+
+func (bridge *bridge) Events(ctx context.Context) <-chan lucifer.Event {
+	channel := make(chan lucifer.Event, 64)
+
+	watcher := &watcher{bridge: bridge, channel: channel}
+	go watcher.watch(ctx)
+
+	return channel
 }
@@ -0,0 +1,69 @@
+package hue
+
+import (
+	"github.com/gissleh/lucifer"
+	"strings"
+)
+
+// gamutsByModel maps the model IDs listed in the Hue documentation to their gamut.
+var gamutsByModel = map[string]lucifer.ColorGamut{
+	"LLC001": lucifer.GamutA,
+	"LLC005": lucifer.GamutA,
+	"LLC006": lucifer.GamutA,
+	"LLC007": lucifer.GamutA,
+	"LLC010": lucifer.GamutA,
+	"LLC011": lucifer.GamutA,
+	"LLC012": lucifer.GamutA,
+	"LLC013": lucifer.GamutA,
+	"LLC014": lucifer.GamutA,
+	"LST001": lucifer.GamutA,
+	"LCT001": lucifer.GamutB,
+	"LCT002": lucifer.GamutB,
+	"LCT003": lucifer.GamutB,
+	"LCT007": lucifer.GamutB,
+	"LLM001": lucifer.GamutB,
+	"LCT010": lucifer.GamutC,
+	"LCT011": lucifer.GamutC,
+	"LCT012": lucifer.GamutC,
+	"LCT014": lucifer.GamutC,
+	"LCT015": lucifer.GamutC,
+	"LCT016": lucifer.GamutC,
+	"LLC020": lucifer.GamutC,
+	"LST002": lucifer.GamutC,
+}
+
+func capabilitiesFor(lightType, modelID string) lucifer.LightCapabilities {
+	capabilities := lucifer.LightCapabilities{}
+
+	switch strings.ToLower(lightType) {
+	case "extended color light":
+		capabilities.Color = true
+		capabilities.ColorTemperature = true
+		capabilities.MinKelvin = 2000
+		capabilities.MaxKelvin = 6500
+		capabilities.Gamut = lucifer.GamutC
+	case "color light":
+		capabilities.Color = true
+		capabilities.Gamut = lucifer.GamutOther
+	case "color temperature light":
+		capabilities.ColorTemperature = true
+		capabilities.MinKelvin = 2200
+		capabilities.MaxKelvin = 6500
+	case "dimmable light":
+	default: // "On/Off light", "On/Off plug-in unit" and unknown types.
+		return capabilities
+	}
+
+	if capabilities.Color {
+		if gamut, ok := gamutsByModel[modelID]; ok {
+			capabilities.Gamut = gamut
+		}
+
+		capabilities.Effects = []string{"colorloop"}
+	}
+
+	capabilities.BrightnessSteps = 254
+	capabilities.Transition = true
+
+	return capabilities
+}
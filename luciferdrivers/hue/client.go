@@ -0,0 +1,324 @@
+package hue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	hue "github.com/collinux/gohue"
+	"github.com/gissleh/lucifer"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpClient is used for all v1 API requests. The timeout keeps a bridge that never answers from
+// blocking callers that do not set a deadline on the context.
+var httpClient = &http.Client{Timeout: time.Second * 30}
+
+// apiError is an error reported by the bridge in the response body.
+type apiError struct {
+	Type        int    `json:"type"`
+	Address     string `json:"address"`
+	Description string `json:"description"`
+}
+
+// driverError converts the error, and finds the matching error in the lucifer package.
+func (err *apiError) driverError() *lucifer.DriverError {
+	driverErr := &lucifer.DriverError{Driver: "hue", Type: err.Type, Address: err.Address, Description: err.Description}
+
+	switch err.Type {
+	case 1:
+		driverErr.Err = lucifer.ErrUnauthorized
+	case 3:
+		switch {
+		case strings.HasPrefix(err.Address, "/lights/"):
+			driverErr.Err = lucifer.ErrLightNotFound
+		case strings.HasPrefix(err.Address, "/sensors/"):
+			driverErr.Err = lucifer.ErrSensorNotFound
+		case strings.HasPrefix(err.Address, "/groups/"):
+			driverErr.Err = lucifer.ErrGroupNotFound
+		case strings.HasPrefix(err.Address, "/scenes/"):
+			driverErr.Err = lucifer.ErrSceneNotFound
+		}
+	case 101:
+		driverErr.Err = lucifer.ErrLinkButtonNotPressed
+	}
+
+	return driverErr
+}
+
+// do sends the request. Failing to reach the bridge is reported as lucifer.ErrBridgeUnreachable,
+// unless it is because the context is done.
+func do(req *http.Request) (*http.Response, error) {
+	res, err := httpClient.Do(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: %v", lucifer.ErrBridgeUnreachable, err)
+	}
+
+	return res, nil
+}
+
+// request sends a request to the bridge at the address and returns the response body. Errors in the
+// response body are returned as *lucifer.DriverError.
+func request(ctx context.Context, addr, method, path string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+addr+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("hue: %s %s: %s: %w", method, path, res.Status, lucifer.ErrRateLimited)
+	} else if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("hue: %s %s: %s", method, path, res.Status)
+	}
+
+	// Errors are sent as a list of results, which is also the format of successful changes.
+	if len(data) > 0 && data[0] == '[' {
+		var results []struct {
+			Error *apiError `json:"error"`
+		}
+		if json.Unmarshal(data, &results) == nil {
+			for _, result := range results {
+				if result.Error != nil {
+					return nil, result.Error.driverError()
+				}
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// fetchInfo gets the bridge's description.
+func fetchInfo(ctx context.Context, addr string) (hue.BridgeInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://"+addr+"/description.xml", nil)
+	if err != nil {
+		return hue.BridgeInfo{}, err
+	}
+
+	res, err := do(req)
+	if err != nil {
+		return hue.BridgeInfo{}, err
+	}
+	defer res.Body.Close()
+
+	info := hue.BridgeInfo{}
+	err = xml.NewDecoder(res.Body).Decode(&info)
+	if err != nil {
+		return hue.BridgeInfo{}, err
+	}
+
+	return info, nil
+}
+
+// bridgeConfig is the part of the bridge's config that can be read without a user.
+type bridgeConfig struct {
+	ModelID    string `json:"modelid"`
+	SWVersion  string `json:"swversion"`
+	APIVersion string `json:"apiversion"`
+}
+
+// fetchConfig gets the bridge's public config.
+func fetchConfig(ctx context.Context, addr string) (bridgeConfig, error) {
+	body, err := request(ctx, addr, "GET", "/api/config", nil)
+	if err != nil {
+		return bridgeConfig{}, err
+	}
+
+	config := bridgeConfig{}
+	err = json.Unmarshal(body, &config)
+	if err != nil {
+		return bridgeConfig{}, err
+	}
+
+	return config, nil
+}
+
+// createUser whitelists a new user on the bridge, which only works shortly after the link button
+// has been pressed.
+func createUser(ctx context.Context, addr string) (string, error) {
+	body, err := request(ctx, addr, "POST", "/api", map[string]string{"devicetype": "github.com/gissleh/lucifer"})
+	if err != nil {
+		return "", err
+	}
+
+	var result []struct {
+		Success struct {
+			Username string `json:"username"`
+		} `json:"success"`
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return "", err
+	}
+	if len(result) == 0 || result[0].Success.Username == "" {
+		return "", fmt.Errorf("unexpected response from bridge: %s", body)
+	}
+
+	return result[0].Success.Username, nil
+}
+
+// request sends a request to the bridge's API on behalf of the user, and decodes the response into
+// out unless it is nil.
+func (bridge *bridge) request(ctx context.Context, method, path string, body, out interface{}) ([]byte, error) {
+	data, err := request(ctx, bridge.gh.IPAddress, method, "/api/"+bridge.gh.Username+path, body)
+
+	bridge.mutex.Lock()
+	if errors.Is(err, lucifer.ErrBridgeUnreachable) {
+		bridge.reachable = false
+	} else if ctx.Err() == nil {
+		bridge.reachable = true
+		bridge.lastSeen = time.Now()
+	}
+	bridge.mutex.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if out != nil {
+		err = json.Unmarshal(data, out)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// lightData is a light as returned by the API, with the fields gohue's light is missing.
+type lightData struct {
+	hue.Light
+	ProductName string `json:"productname"`
+}
+
+// lightsData gets all lights, sorted by index.
+func (bridge *bridge) lightsData(ctx context.Context) ([]lightData, error) {
+	dataMap := make(map[string]lightData)
+	_, err := bridge.request(ctx, "GET", "/lights", nil, &dataMap)
+	if err != nil {
+		return nil, err
+	}
+
+	ghLights := make([]lightData, 0, len(dataMap))
+	for key, ghLight := range dataMap {
+		ghLight.Index, _ = strconv.Atoi(key)
+		ghLights = append(ghLights, ghLight)
+	}
+	sort.Slice(ghLights, func(i, j int) bool {
+		return ghLights[i].Index < ghLights[j].Index
+	})
+
+	return ghLights, nil
+}
+
+// lightData gets the light with the index.
+func (bridge *bridge) lightData(ctx context.Context, index int) (lightData, error) {
+	ghLight := lightData{}
+	_, err := bridge.request(ctx, "GET", "/lights/"+strconv.Itoa(index), nil, &ghLight)
+	if err != nil {
+		return lightData{}, err
+	}
+
+	ghLight.Index = index
+
+	return ghLight, nil
+}
+
+// sensorData is a sensor as returned by the API. gohue's sensor only has the button and daylight state.
+type sensorData struct {
+	State struct {
+		LastUpdated hue.UpdateTime `json:"lastupdated"`
+		ButtonEvent uint16         `json:"buttonevent"`
+		Daylight    *bool          `json:"daylight"`
+		Dark        *bool          `json:"dark"`
+		Presence    *bool          `json:"presence"`
+		Temperature *int           `json:"temperature"`
+		LightLevel  *int           `json:"lightlevel"`
+		Open        *bool          `json:"open"`
+		Humidity    *int           `json:"humidity"`
+	} `json:"state"`
+
+	Config struct {
+		On        bool `json:"on"`
+		Reachable bool `json:"reachable"`
+		Battery   *int `json:"battery"`
+	} `json:"config"`
+
+	Type             string `json:"type"`
+	Name             string `json:"name"`
+	ModelID          string `json:"modelid"`
+	ManufacturerName string `json:"manufacturername"`
+	ProductName      string `json:"productname"`
+	UniqueID         string `json:"uniqueid"`
+	SWVersion        string `json:"swversion"`
+	Index            int    `json:"-"`
+}
+
+// sensorsData gets all sensors, sorted by index.
+func (bridge *bridge) sensorsData(ctx context.Context) ([]sensorData, error) {
+	dataMap := make(map[string]sensorData)
+	_, err := bridge.request(ctx, "GET", "/sensors", nil, &dataMap)
+	if err != nil {
+		return nil, err
+	}
+
+	ghSensors := make([]sensorData, 0, len(dataMap))
+	for key, ghSensor := range dataMap {
+		ghSensor.Index, _ = strconv.Atoi(key)
+		ghSensors = append(ghSensors, ghSensor)
+	}
+	sort.Slice(ghSensors, func(i, j int) bool {
+		return ghSensors[i].Index < ghSensors[j].Index
+	})
+
+	return ghSensors, nil
+}
+
+// sensorData gets the sensor with the index.
+func (bridge *bridge) sensorData(ctx context.Context, index int) (sensorData, error) {
+	ghSensor := sensorData{}
+	_, err := bridge.request(ctx, "GET", "/sensors/"+strconv.Itoa(index), nil, &ghSensor)
+	if err != nil {
+		return sensorData{}, err
+	}
+
+	ghSensor.Index = index
+
+	return ghSensor, nil
+}
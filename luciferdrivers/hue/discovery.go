@@ -0,0 +1,442 @@
+package hue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	hue "github.com/collinux/gohue"
+	"github.com/gissleh/lucifer"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// discoveryTimeout is how long the whole discovery may take, including fetching descriptions.
+const discoveryTimeout = time.Second * 5
+
+const (
+	dnsTypeA   = 1
+	dnsTypePTR = 12
+	dnsTypeTXT = 16
+	dnsTypeSRV = 33
+)
+
+var errInvalidDNSMessage = errors.New("hue: invalid dns message")
+
+// discoverer finds bridges with mDNS and SSDP. The addresses can be changed for testing.
+type discoverer struct {
+	mdnsAddr   string
+	ssdpAddr   string
+	listenTime time.Duration
+	client     *http.Client
+}
+
+var defaultDiscoverer = &discoverer{
+	mdnsAddr:   "224.0.0.251:5353",
+	ssdpAddr:   "239.255.255.250:1900",
+	listenTime: time.Second * 3,
+	client:     &http.Client{Timeout: time.Second * 5},
+}
+
+// discover searches until the context is done or the discovery timeout has passed.
+func (discoverer *discoverer) discover(ctx context.Context) ([]lucifer.DiscoveredBridge, error) {
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	results := make([]lucifer.DiscoveredBridge, 0, 4)
+
+	for _, method := range []func(context.Context) ([]lucifer.DiscoveredBridge, error){discoverer.mdns, discoverer.ssdp} {
+		wg.Add(1)
+
+		go func(method func(context.Context) ([]lucifer.DiscoveredBridge, error)) {
+			defer wg.Done()
+
+			found, err := method(ctx)
+
+			mutex.Lock()
+			if err != nil {
+				errs = append(errs, err)
+			}
+			results = mergeDiscovered(results, found)
+			mutex.Unlock()
+		}(method)
+	}
+
+	wg.Wait()
+
+	// It's only an error if neither method could search.
+	if len(errs) == 2 {
+		return nil, errs[0]
+	}
+
+	return results, nil
+}
+
+func (discoverer *discoverer) mdns(ctx context.Context) ([]lucifer.DiscoveredBridge, error) {
+	listenCtx, cancel := context.WithTimeout(ctx, discoverer.listenTime)
+	defer cancel()
+
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	addr, err := net.ResolveUDPAddr("udp4", discoverer.mdnsAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.WriteTo(mdnsQuery("_hue._tcp.local."), addr)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]lucifer.DiscoveredBridge, 0, 4)
+	err = readUntilDone(listenCtx, conn, func(data []byte, from net.Addr) {
+		bridge, err := parseMDNSResponse(data)
+		if err != nil || bridge.ID == "" {
+			return
+		}
+		if bridge.Address == "" {
+			bridge.Address = from.(*net.UDPAddr).IP.String()
+		}
+
+		results = mergeDiscovered(results, []lucifer.DiscoveredBridge{bridge})
+	})
+
+	return results, err
+}
+
+func (discoverer *discoverer) ssdp(ctx context.Context) ([]lucifer.DiscoveredBridge, error) {
+	listenCtx, cancel := context.WithTimeout(ctx, discoverer.listenTime)
+	defer cancel()
+
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	addr, err := net.ResolveUDPAddr("udp4", discoverer.ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n" +
+		"ST: upnp:rootdevice\r\n\r\n"
+	_, err = conn.WriteTo([]byte(search), addr)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make(map[string]string)
+	err = readUntilDone(listenCtx, conn, func(data []byte, from net.Addr) {
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+		if err != nil {
+			return
+		}
+		resp.Body.Close()
+
+		bridgeID := resp.Header.Get("hue-bridgeid")
+		if bridgeID == "" && !strings.Contains(resp.Header.Get("Server"), "IpBridge") {
+			return
+		}
+
+		locations[resp.Header.Get("Location")] = bridgeID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The responses don't include much, so the details are found in the description.
+	results := make([]lucifer.DiscoveredBridge, 0, len(locations))
+	for location, bridgeID := range locations {
+		bridge, err := discoverer.describe(ctx, location)
+		if err != nil {
+			continue
+		}
+		if bridge.ID == "" {
+			bridge.ID = serialFromBridgeID(bridgeID)
+		}
+
+		results = mergeDiscovered(results, []lucifer.DiscoveredBridge{bridge})
+	}
+
+	return results, nil
+}
+
+// describe fetches the description.xml at the location.
+func (discoverer *discoverer) describe(ctx context.Context, location string) (lucifer.DiscoveredBridge, error) {
+	locationURL, err := url.Parse(location)
+	if err != nil {
+		return lucifer.DiscoveredBridge{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", location, nil)
+	if err != nil {
+		return lucifer.DiscoveredBridge{}, err
+	}
+
+	resp, err := discoverer.client.Do(req)
+	if err != nil {
+		return lucifer.DiscoveredBridge{}, err
+	}
+	defer resp.Body.Close()
+
+	info := hue.BridgeInfo{}
+	err = xml.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return lucifer.DiscoveredBridge{}, err
+	}
+
+	address := locationURL.Host
+	if locationURL.Port() == "80" {
+		address = locationURL.Hostname()
+	}
+
+	return lucifer.DiscoveredBridge{
+		Address: address,
+		ID:      strings.ToLower(info.Device.SerialNumber),
+		Model:   info.Device.ModelNumber,
+		Name:    info.Device.FriendlyName,
+	}, nil
+}
+
+// readUntilDone calls cb for each packet received until the context is done.
+func readUntilDone(ctx context.Context, conn net.PacketConn, cb func(data []byte, from net.Addr)) error {
+	deadline, _ := ctx.Deadline()
+	buffer := make([]byte, 9000)
+
+	for {
+		err := conn.SetReadDeadline(deadline)
+		if err != nil {
+			return err
+		}
+
+		n, from, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return nil
+			}
+
+			return err
+		}
+
+		cb(buffer[:n], from)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// mergeDiscovered adds the bridges to the list, filling in the blanks for those already there.
+func mergeDiscovered(list []lucifer.DiscoveredBridge, bridges []lucifer.DiscoveredBridge) []lucifer.DiscoveredBridge {
+	for _, bridge := range bridges {
+		found := false
+		for i := range list {
+			if list[i].ID != bridge.ID {
+				continue
+			}
+
+			if list[i].Address == "" {
+				list[i].Address = bridge.Address
+			}
+			if list[i].Model == "" {
+				list[i].Model = bridge.Model
+			}
+			if list[i].Name == "" {
+				list[i].Name = bridge.Name
+			}
+
+			found = true
+			break
+		}
+
+		if !found {
+			list = append(list, bridge)
+		}
+	}
+
+	return list
+}
+
+// serialFromBridgeID converts the bridge ID (e.g. 001788FFFE23BFC2) to the serial number used as
+// the bridge's ID in this driver (e.g. 00178823bfc2).
+func serialFromBridgeID(bridgeID string) string {
+	bridgeID = strings.ToLower(bridgeID)
+	if len(bridgeID) == 16 && bridgeID[6:10] == "fffe" {
+		return bridgeID[:6] + bridgeID[10:]
+	}
+
+	return bridgeID
+}
+
+// mdnsQuery builds a PTR query asking for unicast responses.
+func mdnsQuery(name string) []byte {
+	msg := make([]byte, 12, 64)
+	binary.BigEndian.PutUint16(msg[4:], 1) // QDCOUNT
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+
+	msg = append(msg, 0, dnsTypePTR)
+	msg = append(msg, 0x80, 1) // IN with the unicast-response bit.
+
+	return msg
+}
+
+// parseMDNSResponse finds the bridge in the answer and additional records.
+func parseMDNSResponse(msg []byte) (lucifer.DiscoveredBridge, error) {
+	if len(msg) < 12 {
+		return lucifer.DiscoveredBridge{}, errInvalidDNSMessage
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	records := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+	offset := 12
+
+	for i := 0; i < questions; i++ {
+		_, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return lucifer.DiscoveredBridge{}, err
+		}
+
+		offset = next + 4
+	}
+
+	bridge := lucifer.DiscoveredBridge{}
+	isHue := false
+	target := ""
+	addresses := make(map[string]string)
+
+	for i := 0; i < records; i++ {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return lucifer.DiscoveredBridge{}, err
+		}
+		if next+10 > len(msg) {
+			return lucifer.DiscoveredBridge{}, errInvalidDNSMessage
+		}
+
+		recordType := binary.BigEndian.Uint16(msg[next:])
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		end := start + length
+		if end > len(msg) {
+			return lucifer.DiscoveredBridge{}, errInvalidDNSMessage
+		}
+
+		switch recordType {
+		case dnsTypePTR:
+			if strings.EqualFold(name, "_hue._tcp.local.") {
+				instance, _, err := readDNSName(msg, start)
+				if err != nil {
+					return lucifer.DiscoveredBridge{}, err
+				}
+
+				isHue = true
+				bridge.Name = strings.TrimSuffix(instance, "._hue._tcp.local.")
+			}
+		case dnsTypeSRV:
+			if length > 6 {
+				target, _, err = readDNSName(msg, start+6)
+				if err != nil {
+					return lucifer.DiscoveredBridge{}, err
+				}
+			}
+		case dnsTypeTXT:
+			for pos := start; pos < end; {
+				textLength := int(msg[pos])
+				if pos+1+textLength > end {
+					break
+				}
+
+				text := string(msg[pos+1 : pos+1+textLength])
+				if strings.HasPrefix(text, "bridgeid=") {
+					bridge.ID = serialFromBridgeID(text[9:])
+				} else if strings.HasPrefix(text, "modelid=") {
+					bridge.Model = text[8:]
+				}
+
+				pos += 1 + textLength
+			}
+		case dnsTypeA:
+			if length == 4 {
+				addresses[strings.ToLower(name)] = net.IP(msg[start:end]).String()
+			}
+		}
+
+		offset = end
+	}
+
+	if !isHue {
+		return lucifer.DiscoveredBridge{}, nil
+	}
+
+	bridge.Address = addresses[strings.ToLower(target)]
+
+	return bridge, nil
+}
+
+// readDNSName reads a possibly compressed name, and returns it with the offset after it.
+func readDNSName(msg []byte, offset int) (string, int, error) {
+	sb := strings.Builder{}
+	next := -1
+
+	for jumps := 0; jumps < 16; {
+		if offset >= len(msg) {
+			return "", 0, errInvalidDNSMessage
+		}
+
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			if sb.Len() == 0 {
+				sb.WriteByte('.')
+			}
+
+			return sb.String(), next, nil
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(msg) {
+				return "", 0, errInvalidDNSMessage
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3FFF)
+			jumps++
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errInvalidDNSMessage
+			}
+
+			sb.Write(msg[offset+1 : offset+1+length])
+			sb.WriteByte('.')
+			offset += 1 + length
+		}
+	}
+
+	return "", 0, fmt.Errorf("hue: too many compression pointers in dns name")
+}
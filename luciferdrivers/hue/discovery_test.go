@@ -0,0 +1,129 @@
+package hue
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/gissleh/lucifer"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeResponder answers every packet it receives with the response.
+func fakeResponder(t *testing.T, response func(query []byte) []byte) (string, func()) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		buffer := make([]byte, 9000)
+		for {
+			n, from, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			_, _ = conn.WriteTo(response(buffer[:n]), from)
+		}
+	}()
+
+	return conn.LocalAddr().String(), func() { _ = conn.Close() }
+}
+
+func dnsName(name string) []byte {
+	data := make([]byte, 0, len(name)+2)
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		data = append(data, byte(len(label)))
+		data = append(data, label...)
+	}
+
+	return append(data, 0)
+}
+
+func dnsRecord(name string, recordType uint16, data []byte) []byte {
+	record := dnsName(name)
+	record = append(record, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(record[len(record)-10:], recordType)
+	binary.BigEndian.PutUint16(record[len(record)-8:], 1)
+	binary.BigEndian.PutUint16(record[len(record)-2:], uint16(len(data)))
+
+	return append(record, data...)
+}
+
+func fakeMDNSResponse(query []byte) []byte {
+	instance := "Philips Hue - 23BFC2._hue._tcp.local."
+
+	srv := append([]byte{0, 0, 0, 0, 1, 187}, dnsName("001788fffe23bfc2.local.")...)
+	txt := []byte("\x19bridgeid=001788fffe23bfc2\x0emodelid=BSB002")
+
+	msg := []byte{0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 3}
+	msg = append(msg, dnsRecord("_hue._tcp.local.", dnsTypePTR, dnsName(instance))...)
+	msg = append(msg, dnsRecord(instance, dnsTypeSRV, srv)...)
+	msg = append(msg, dnsRecord(instance, dnsTypeTXT, txt)...)
+	msg = append(msg, dnsRecord("001788fffe23bfc2.local.", dnsTypeA, []byte{192, 168, 1, 2})...)
+
+	return msg
+}
+
+func TestDiscoverer_MDNS(t *testing.T) {
+	mdnsAddr, closeMDNS := fakeResponder(t, fakeMDNSResponse)
+	defer closeMDNS()
+
+	discoverer := &discoverer{mdnsAddr: mdnsAddr, listenTime: time.Millisecond * 200}
+
+	bridges, err := discoverer.mdns(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []lucifer.DiscoveredBridge{{
+		Address: "192.168.1.2",
+		ID:      "00178823bfc2",
+		Model:   "BSB002",
+		Name:    "Philips Hue - 23BFC2",
+	}}, bridges)
+}
+
+func TestDiscoverer_Discover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8" ?>
+<root xmlns="urn:schemas-upnp-org:device-1-0"><device>
+<friendlyName>Hue Bridge (127.0.0.1)</friendlyName>
+<modelNumber>BSB002</modelNumber>
+<serialNumber>0017884a5b6c</serialNumber>
+</device></root>`)
+	}))
+	defer server.Close()
+
+	ssdpAddr, closeSSDP := fakeResponder(t, func(query []byte) []byte {
+		if !strings.HasPrefix(string(query), "M-SEARCH") {
+			return nil
+		}
+
+		return []byte("HTTP/1.1 200 OK\r\n" +
+			"LOCATION: " + server.URL + "/description.xml\r\n" +
+			"SERVER: Hue/1.0 UPnP/1.0 IpBridge/1.41.0\r\n" +
+			"hue-bridgeid: 0017884A5B6C\r\n\r\n")
+	})
+	defer closeSSDP()
+
+	mdnsAddr, closeMDNS := fakeResponder(t, fakeMDNSResponse)
+	defer closeMDNS()
+
+	discoverer := &discoverer{
+		mdnsAddr:   mdnsAddr,
+		ssdpAddr:   ssdpAddr,
+		listenTime: time.Millisecond * 200,
+		client:     server.Client(),
+	}
+
+	bridges, err := discoverer.discover(context.Background())
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []lucifer.DiscoveredBridge{
+		{Address: "192.168.1.2", ID: "00178823bfc2", Model: "BSB002", Name: "Philips Hue - 23BFC2"},
+		{Address: strings.TrimPrefix(server.URL, "http://"), ID: "0017884a5b6c", Model: "BSB002", Name: "Hue Bridge (127.0.0.1)"},
+	}, bridges)
+}
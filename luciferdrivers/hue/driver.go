@@ -2,42 +2,81 @@ package hue
 
 import (
 	"context"
-	"github.com/collinux/gohue"
+	"errors"
+	hue "github.com/collinux/gohue"
 	"github.com/gissleh/lucifer"
-	"net"
 	"sync"
 	"time"
 )
 
-func New() lucifer.Driver {
-	return &driver{
+// An Option configures the driver.
+type Option func(driver *driver)
+
+// WithStateCache lets lights reuse their last fetched state for up to ttl, instead of fetching it
+// from the bridge on every State and SetState.
+func WithStateCache(ttl time.Duration) Option {
+	return func(driver *driver) {
+		driver.stateTTL = ttl
+	}
+}
+
+// WithRateLimit changes how many commands per second are sent to each bridge. The default is
+// DefaultRateLimit, and zero turns the limit off.
+func WithRateLimit(perSecond float64) Option {
+	return func(driver *driver) {
+		driver.rateLimit = perSecond
+	}
+}
+
+// DefaultRateLimit is the number of commands per second that bridges handle without dropping any.
+const DefaultRateLimit = 10
+
+func New(options ...Option) lucifer.Driver {
+	driver := &driver{
+		rateLimit:  DefaultRateLimit,
 		bridgeList: make([]*bridge, 0, 64),
 		bridgeMap:  make(map[string]*bridge, 64),
+		queues:     make(map[string]*lucifer.WriteQueue, 64),
 	}
+	for _, option := range options {
+		option(driver)
+	}
+
+	return driver
 }
 
 type driver struct {
+	stateTTL  time.Duration
+	rateLimit float64
+
 	mutex      sync.Mutex
 	bridgeList []*bridge
 	bridgeMap  map[string]*bridge
+	queues     map[string]*lucifer.WriteQueue
 }
 
 func (driver *driver) SetupBridge(ctx context.Context, ip string) (lucifer.Bridge, string, error) {
-	ghBridge, err := hue.NewBridge(ip)
+	info, err := fetchInfo(ctx, ip)
+	if err != nil {
+		return nil, "", err
+	}
+	config, err := fetchConfig(ctx, ip)
 	if err != nil {
 		return nil, "", err
 	}
 
 	var key string
 	for {
-		newKey, err := ghBridge.CreateUser("github.com/gissleh/lucifer")
+		key, err = createUser(ctx, ip)
 		if err == nil {
-			if _, ok := err.(net.Error); ok {
-				return nil, "", err
+			break
+		}
+		if !errors.Is(err, lucifer.ErrLinkButtonNotPressed) {
+			if ctx.Err() != nil {
+				return nil, "", ctx.Err()
 			}
 
-			key = newKey
-			break
+			return nil, "", err
 		}
 
 		select {
@@ -47,12 +86,7 @@ func (driver *driver) SetupBridge(ctx context.Context, ip string) (lucifer.Bridg
 		}
 	}
 
-	err = ghBridge.Login(key)
-	if err != nil {
-		return nil, key, err
-	}
-
-	bridge := &bridge{gh: ghBridge}
+	bridge := driver.newBridge(ip, key, info, config)
 
 	driver.mutex.Lock()
 	driver.bridgeList = append(driver.bridgeList, bridge)
@@ -63,17 +97,22 @@ func (driver *driver) SetupBridge(ctx context.Context, ip string) (lucifer.Bridg
 }
 
 func (driver *driver) AddBridge(ctx context.Context, ip, key string) (lucifer.Bridge, error) {
-	ghBridge, err := hue.NewBridge(ip)
+	info, err := fetchInfo(ctx, ip)
 	if err != nil {
 		return nil, err
 	}
-
-	err = ghBridge.Login(key)
+	config, err := fetchConfig(ctx, ip)
 	if err != nil {
 		return nil, err
 	}
 
-	bridge := &bridge{gh: ghBridge}
+	bridge := driver.newBridge(ip, key, info, config)
+
+	// The config is public, but the lights are only listed if the key is whitelisted.
+	_, err = bridge.request(ctx, "GET", "/lights", nil, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	driver.mutex.Lock()
 	driver.bridgeList = append(driver.bridgeList, bridge)
@@ -83,6 +122,25 @@ func (driver *driver) AddBridge(ctx context.Context, ip, key string) (lucifer.Br
 	return bridge, nil
 }
 
+// newBridge creates a bridge with the driver's options. Bridges with the same ID share the write
+// queue, so that they are rate limited together.
+func (driver *driver) newBridge(ip, key string, info hue.BridgeInfo, config bridgeConfig) *bridge {
+	bridge := newBridge(ip, key, info, config)
+	bridge.stateTTL = driver.stateTTL
+
+	driver.mutex.Lock()
+	queue, ok := driver.queues[bridge.ID()]
+	if !ok {
+		queue = lucifer.NewWriteQueue(lucifer.NewRateLimiter(driver.rateLimit))
+		driver.queues[bridge.ID()] = queue
+	}
+	driver.mutex.Unlock()
+
+	bridge.queue = queue
+
+	return bridge
+}
+
 func (driver *driver) RemoveBridge(ctx context.Context, id string) error {
 	bridge := driver.Bridge(id)
 	if bridge == nil {
@@ -93,9 +151,11 @@ func (driver *driver) RemoveBridge(ctx context.Context, id string) error {
 	for i := range driver.bridgeList {
 		if driver.bridgeList[i] == bridge {
 			driver.bridgeList = append(driver.bridgeList[:i], driver.bridgeList[i+1:]...)
+			break
 		}
-		delete(driver.bridgeMap, id)
 	}
+	delete(driver.bridgeMap, id)
+	delete(driver.queues, id)
 	driver.mutex.Unlock()
 
 	return nil
@@ -106,6 +166,11 @@ func (driver *driver) Bridge(id string) lucifer.Bridge {
 	bridge := driver.bridgeMap[id]
 	driver.mutex.Unlock()
 
+	// A nil *bridge would not be a nil lucifer.Bridge.
+	if bridge == nil {
+		return nil
+	}
+
 	return bridge
 }
 
@@ -119,3 +184,7 @@ func (driver *driver) Bridges() []lucifer.Bridge {
 
 	return list
 }
+
+func (driver *driver) Discover(ctx context.Context) ([]lucifer.DiscoveredBridge, error) {
+	return defaultDiscoverer.discover(ctx)
+}
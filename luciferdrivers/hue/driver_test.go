@@ -0,0 +1,655 @@
+package hue_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gissleh/lucifer"
+	"github.com/gissleh/lucifer/luciferdrivers/hue"
+	"github.com/gissleh/lucifer/luciferdrivers/hue/huetest"
+	"github.com/gissleh/lucifer/lucifertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFakeBridge(t *testing.T) (*huetest.Server, lucifer.Bridge) {
+	server := huetest.NewServer()
+	server.AddUser("key")
+
+	bridge, err := hue.New().AddBridge(context.Background(), server.Addr(), "key")
+	require.NoError(t, err)
+
+	return server, bridge
+}
+
+func TestDriver(t *testing.T) {
+	servers := make([]*huetest.Server, 0, 16)
+	defer func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}()
+
+	lucifertest.TestDriver(t, lucifertest.Harness{
+		New: func(t *testing.T) (lucifer.Driver, string) {
+			server := huetest.NewServer()
+			server.AddLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:01-0b", "Color Light"))
+			server.AddLight(huetest.ColorTemperatureLight("00:17:88:01:00:00:00:02-0b", "White Ambiance Light"))
+			server.AddLight(huetest.DimmableLight("00:17:88:01:00:00:00:03-0b", "Dimmable Light"))
+			server.AddSensor(huetest.DimmerSwitch("00:17:88:01:00:00:00:04-02-fc00", "Dimmer Switch"))
+			server.AddSensor(huetest.DaylightSensor())
+			server.AddSensor(huetest.PresenceSensor("00:17:88:01:00:00:00:05-02-0406", "Motion Sensor"))
+			server.AddSensor(huetest.TemperatureSensor("00:17:88:01:00:00:00:05-02-0402", "Motion Sensor Temperature", 2150))
+			server.PressLinkButton()
+
+			servers = append(servers, server)
+
+			return hue.New(), server.Addr()
+		},
+		PressButton: func(t *testing.T, sensor lucifer.Sensor, button int) {
+			server := servers[len(servers)-1]
+
+			index, ok := server.SensorIndex(sensor.ID())
+			require.True(t, ok, "sensor on fake bridge")
+
+			server.SetButtonEvent(index, button*1000+2)
+		},
+	})
+}
+
+func TestDriver_SetupBridge(t *testing.T) {
+	server := huetest.NewServer()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer cancel()
+
+	_, _, err := hue.New().SetupBridge(ctx, server.Addr())
+	assert.Equal(t, context.DeadlineExceeded, err, "without link button")
+
+	go func() {
+		time.Sleep(time.Millisecond * 500)
+		server.PressLinkButton()
+	}()
+
+	bridge, key, err := hue.New().SetupBridge(context.Background(), server.Addr())
+	require.NoError(t, err, "with link button")
+	assert.NotEmpty(t, key)
+	assert.Equal(t, server.SerialNumber(), bridge.ID())
+}
+
+func TestBridge_DiscoverLights(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.AddLight(huetest.DimmableLight("00:17:88:01:00:00:00:01-0b", "Old Light"))
+	server.AddPendingLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:02-0b", "New Light"))
+
+	lights, err := bridge.DiscoverLights(context.Background())
+	require.NoError(t, err)
+	if assert.Len(t, lights, 1) {
+		assert.Equal(t, "00:17:88:01:00:00:00:02-0b", lights[0].ID())
+		assert.Equal(t, "New Light", lights[0].Name())
+	}
+}
+
+func TestBridge_DiscoverSensors(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.ScanDuration = time.Millisecond * 500
+	server.AddSensor(huetest.DimmerSwitch("00:17:88:01:00:00:00:01-02-fc00", "Old Switch"))
+	server.AddPendingSensor(huetest.PresenceSensor("00:17:88:01:00:00:00:02-02-0406", "New Motion Sensor"))
+
+	started := time.Now()
+	sensors, err := bridge.DiscoverSensors(context.Background())
+	require.NoError(t, err)
+	assert.True(t, time.Since(started) >= server.ScanDuration, "returned before the scan was over")
+	if assert.Len(t, sensors, 1) {
+		assert.Equal(t, "00:17:88:01:00:00:00:02-02-0406", sensors[0].ID())
+		assert.Equal(t, lucifer.SensorKindPresence, sensors[0].Kind())
+	}
+}
+
+func TestBridge_SearchDevices(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.ScanDuration = time.Millisecond * 1500
+	server.AddPendingLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:01-0b", "New Light"))
+	server.AddSerialLight("A1B2C3", huetest.DimmableLight("00:17:88:01:00:00:00:02-0b", "Other Bridge's Light"))
+	server.AddSerialLight("D4E5F6", huetest.DimmableLight("00:17:88:01:00:00:00:03-0b", "Not Searched For"))
+
+	found := make([]string, 0, 3)
+	progress := 0
+	var last lucifer.SearchEvent
+	for event := range bridge.SearchDevices(context.Background(), lucifer.DeviceSearch{Serials: []string{"A1B2C3"}}) {
+		switch event.Kind {
+		case lucifer.SearchEventLightFound:
+			found = append(found, event.Light.ID())
+		case lucifer.SearchEventSensorFound:
+			found = append(found, event.Sensor.ID())
+		case lucifer.SearchEventProgress:
+			if progress == 0 {
+				// A device that joins during the search is streamed when it appears.
+				server.AddSensor(huetest.DimmerSwitch("00:17:88:01:00:00:00:04-02-fc00", "Dimmer Switch"))
+			}
+			progress++
+			assert.True(t, event.Progress >= 0 && event.Progress < 1, "progress %f", event.Progress)
+		}
+
+		last = event
+	}
+
+	assert.Equal(t, []string{
+		"00:17:88:01:00:00:00:01-0b",
+		"00:17:88:01:00:00:00:02-0b",
+		"00:17:88:01:00:00:00:04-02-fc00",
+	}, found)
+	assert.True(t, progress > 0, "progress events")
+	assert.Equal(t, lucifer.SearchEvent{Kind: lucifer.SearchEventDone, Progress: 1}, last)
+}
+
+func TestBridge_DiscoverLights_Cancel(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.ScanDuration = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	started := time.Now()
+	_, err := bridge.DiscoverLights(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	assert.True(t, time.Since(started) < time.Second, "DiscoverLights returned after the deadline")
+}
+
+func TestBridge_SearchDevices_Cancel(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.ScanDuration = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	var last lucifer.SearchEvent
+	for event := range bridge.SearchDevices(ctx, lucifer.DeviceSearch{}) {
+		last = event
+	}
+	assert.Equal(t, lucifer.SearchEventDone, last.Kind)
+	assert.True(t, errors.Is(last.Err, context.DeadlineExceeded), "got %v", last.Err)
+}
+
+func TestBridge_QueueLen(t *testing.T) {
+	server := huetest.NewServer()
+	server.AddUser("key")
+	defer server.Close()
+
+	bridge, err := hue.New(hue.WithRateLimit(5)).AddBridge(context.Background(), server.Addr(), "key")
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		server.AddLight(huetest.DimmableLight(fmt.Sprintf("00:17:88:01:00:00:00:%02d-0b", i), "Light"))
+	}
+	lights, err := bridge.Lights(context.Background())
+	require.NoError(t, err)
+
+	reporter, ok := bridge.(lucifer.QueueReporter)
+	require.True(t, ok)
+	assert.Equal(t, 0, reporter.QueueLen())
+
+	done := make(chan error, len(lights))
+	for _, light := range lights {
+		go func(light lucifer.Light) {
+			done <- light.SetState(context.Background(), lucifer.LightState{Power: true, Brightness: 1})
+		}(light)
+	}
+
+	assert.Eventually(t, func() bool { return reporter.QueueLen() > 0 }, time.Second, time.Millisecond)
+	for range lights {
+		require.NoError(t, <-done)
+	}
+	assert.Equal(t, 0, reporter.QueueLen())
+}
+
+func TestLight_SetState(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	colorIndex := server.AddLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:01-0b", "Color Light"))
+	ambianceIndex := server.AddLight(huetest.ColorTemperatureLight("00:17:88:01:00:00:00:02-0b", "White Ambiance Light"))
+	livingColorsIndex := server.AddLight(huetest.ColorLight("00:17:88:01:00:00:00:03-0b", "LivingColors"))
+
+	table := []struct {
+		light    string
+		index    string
+		state    lucifer.LightState
+		expected map[string]interface{}
+	}{
+		{
+			light: "00:17:88:01:00:00:00:01-0b", index: colorIndex,
+			state:    lucifer.LightState{Power: true, Brightness: 0.5, Color: lucifer.MustParseColor("#ff0000")},
+			expected: map[string]interface{}{"on": true, "bri": 127.0, "xy": []interface{}{0.64, 0.33}},
+		},
+		{
+			light: "00:17:88:01:00:00:00:01-0b", index: colorIndex,
+			state:    lucifer.LightState{Power: true, Brightness: 1, Color: lucifer.MustParseColor("2000k"), Transition: time.Second * 2},
+			expected: map[string]interface{}{"on": true, "bri": 254.0, "ct": 500.0, "transitiontime": 20.0},
+		},
+		{
+			light: "00:17:88:01:00:00:00:02-0b", index: ambianceIndex,
+			state:    lucifer.LightState{Power: true, Brightness: 0.5, Color: lucifer.MustParseColor("#9fbfff")},
+			expected: map[string]interface{}{"on": true, "bri": 127.0, "ct": 153.0},
+		},
+		{
+			light: "00:17:88:01:00:00:00:03-0b", index: livingColorsIndex,
+			state:    lucifer.LightState{Power: true, Brightness: 1, Color: lucifer.MustParseColor("2700k")},
+			expected: map[string]interface{}{"on": true, "bri": 254.0, "xy": []interface{}{0.4639, 0.4085}},
+		},
+		{
+			light: "00:17:88:01:00:00:00:02-0b", index: ambianceIndex,
+			state:    lucifer.LightState{Power: false, Transition: time.Millisecond * 50},
+			expected: map[string]interface{}{"on": false, "transitiontime": 0.0},
+		},
+	}
+
+	for _, row := range table {
+		light, err := bridge.Light(context.Background(), row.light)
+		require.NoError(t, err)
+		before := len(server.Requests())
+		require.NoError(t, light.SetState(context.Background(), row.state))
+
+		requests := server.Requests()
+		assert.Equal(t, before+2, len(requests), "the light is fetched before the change, but not after")
+		last := requests[len(requests)-1]
+		assert.Equal(t, "PUT", last.Method)
+		assert.True(t, strings.HasSuffix(last.Path, "/lights/"+row.index+"/state"), last.Path)
+
+		body := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(last.Body, &body))
+		assert.Equal(t, row.expected, body)
+	}
+}
+
+func TestSensor_State(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	index := server.AddSensor(huetest.DimmerSwitch("00:17:88:01:00:00:00:04-02-fc00", "Dimmer Switch"))
+
+	sensor, err := bridge.Sensor(context.Background(), "00:17:88:01:00:00:00:04-02-fc00")
+	require.NoError(t, err)
+	assert.True(t, sensor.IsButton())
+
+	table := []struct {
+		buttonEvent int
+		expected    []lucifer.SensorStateButtonEvent
+	}{
+		{1000, []lucifer.SensorStateButtonEvent{{Button: 1, Kind: lucifer.ButtonEventPress}}},
+		{1002, nil},
+		{4002, []lucifer.SensorStateButtonEvent{{Button: 4, Kind: lucifer.ButtonEventPress}}},
+		{2001, []lucifer.SensorStateButtonEvent{{Button: 2, Kind: lucifer.ButtonEventHold}}},
+		{2003, []lucifer.SensorStateButtonEvent{{Button: 2, Kind: lucifer.ButtonEventRelease}}},
+		{2002, []lucifer.SensorStateButtonEvent{{Button: 2, Kind: lucifer.ButtonEventPress}}},
+	}
+
+	for _, row := range table {
+		server.SetButtonEvent(index, row.buttonEvent)
+
+		state, err := sensor.State(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, row.expected, state.ButtonEvents, "button event %d", row.buttonEvent)
+	}
+}
+
+func TestSensor_State_Tap(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	index := server.AddSensor(huetest.TapSwitch("00:00:00:00:00:40:00:01-f2", "Tap"))
+
+	sensor, err := bridge.Sensor(context.Background(), "00:00:00:00:00:40:00:01-f2")
+	require.NoError(t, err)
+	assert.Equal(t, lucifer.SensorKindButton, sensor.Kind())
+
+	table := []struct {
+		buttonEvent int
+		button      int
+	}{
+		{34, 1},
+		{16, 2},
+		{17, 3},
+		{18, 4},
+		{18, 4},
+	}
+
+	for _, row := range table {
+		server.SetButtonEvent(index, row.buttonEvent)
+
+		state, err := sensor.State(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []lucifer.SensorStateButtonEvent{{Button: row.button, Kind: lucifer.ButtonEventPress}}, state.ButtonEvents, "button event %d", row.buttonEvent)
+	}
+}
+
+func TestSensor_State_Kinds(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.AddSensor(huetest.PresenceSensor("00:17:88:01:00:00:00:05-02-0406", "Motion Sensor"))
+	server.AddSensor(huetest.TemperatureSensor("00:17:88:01:00:00:00:05-02-0402", "Temperature", 2150))
+	server.AddSensor(huetest.LightLevelSensor("00:17:88:01:00:00:00:05-02-0400", "Light Level", 20001))
+	server.AddSensor(huetest.OpenCloseSensor("open-close-1", "Door"))
+	server.AddSensor(huetest.HumiditySensor("humidity-1", "Humidity", 4525))
+
+	table := []struct {
+		id       string
+		kind     lucifer.SensorKind
+		expected func(state lucifer.SensorState)
+	}{
+		{"00:17:88:01:00:00:00:05-02-0406", lucifer.SensorKindPresence, func(state lucifer.SensorState) {
+			if assert.NotNil(t, state.Presence) {
+				assert.False(t, *state.Presence)
+			}
+		}},
+		{"00:17:88:01:00:00:00:05-02-0402", lucifer.SensorKindTemperature, func(state lucifer.SensorState) {
+			if assert.NotNil(t, state.Temperature) {
+				assert.InDelta(t, 21.5, *state.Temperature, 0.001)
+			}
+		}},
+		{"00:17:88:01:00:00:00:05-02-0400", lucifer.SensorKindLightLevel, func(state lucifer.SensorState) {
+			if assert.NotNil(t, state.LightLevel) && assert.NotNil(t, state.Daylight) {
+				assert.InDelta(t, 100, *state.LightLevel, 0.01)
+				assert.True(t, *state.Daylight)
+			}
+		}},
+		{"open-close-1", lucifer.SensorKindContact, func(state lucifer.SensorState) {
+			if assert.NotNil(t, state.Open) {
+				assert.False(t, *state.Open)
+			}
+		}},
+		{"humidity-1", lucifer.SensorKindHumidity, func(state lucifer.SensorState) {
+			if assert.NotNil(t, state.Humidity) {
+				assert.InDelta(t, 45.25, *state.Humidity, 0.001)
+			}
+		}},
+	}
+
+	for _, row := range table {
+		sensor, err := bridge.Sensor(context.Background(), row.id)
+		require.NoError(t, err)
+		assert.Equal(t, row.kind, sensor.Kind(), row.id)
+
+		state, err := sensor.State(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, state.ButtonEvents, row.id)
+		row.expected(state)
+	}
+}
+
+func TestGroup_SetState(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.AddLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:01-0b", "Color Light"))
+	server.AddLight(huetest.ColorTemperatureLight("00:17:88:01:00:00:00:02-0b", "White Ambiance Light"))
+	lights, err := bridge.Lights(context.Background())
+	require.NoError(t, err)
+	group, err := bridge.CreateGroup(context.Background(), "Group", lucifer.GroupKindRoom, lights)
+	require.NoError(t, err)
+
+	table := []struct {
+		state    lucifer.LightState
+		expected map[string]interface{}
+	}{
+		{
+			state:    lucifer.LightState{Power: true, Brightness: 1, Color: lucifer.MustParseColor("#9fbfff")},
+			expected: map[string]interface{}{"on": true, "bri": 254.0, "xy": []interface{}{0.249, 0.2533}, "ct": 153.0},
+		},
+		{
+			state:    lucifer.LightState{Power: true, Brightness: 1, Color: lucifer.MustParseColor("2000k")},
+			expected: map[string]interface{}{"on": true, "bri": 254.0, "ct": 500.0},
+		},
+	}
+
+	for _, row := range table {
+		require.NoError(t, group.SetState(context.Background(), row.state))
+
+		requests := server.Requests()
+		last := requests[len(requests)-1]
+		assert.Equal(t, "PUT", last.Method)
+		assert.True(t, strings.HasSuffix(last.Path, "/groups/"+group.ID()+"/action"), last.Path)
+
+		body := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(last.Body, &body))
+		assert.Equal(t, row.expected, body)
+	}
+}
+
+func TestLight_Update(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.AddLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:01-0b", "Color Light"))
+	server.AddLight(huetest.ColorLight("00:17:88:01:00:00:00:03-0b", "LivingColors"))
+	light, err := bridge.Light(context.Background(), "00:17:88:01:00:00:00:01-0b")
+	require.NoError(t, err)
+	livingColors, err := bridge.Light(context.Background(), "00:17:88:01:00:00:00:03-0b")
+	require.NoError(t, err)
+
+	power := true
+	brightness := 0.5
+	kelvin := 2000
+	red := lucifer.MustParseColor("#ff0000")
+
+	table := []struct {
+		light    lucifer.Light
+		update   lucifer.LightUpdate
+		expected map[string]interface{}
+	}{
+		{light, lucifer.LightUpdate{Power: &power}, map[string]interface{}{"on": true}},
+		{light, lucifer.LightUpdate{Brightness: &brightness, Transition: time.Second}, map[string]interface{}{"bri": 127.0, "transitiontime": 10.0}},
+		{light, lucifer.LightUpdate{Kelvin: &kelvin, Color: &red}, map[string]interface{}{"ct": 500.0}},
+		{light, lucifer.LightUpdate{Color: &red}, map[string]interface{}{"xy": []interface{}{0.64, 0.33}}},
+		{livingColors, lucifer.LightUpdate{Power: &power, Kelvin: &kelvin}, map[string]interface{}{"on": true, "xy": []interface{}{0.5284, 0.4136}}},
+	}
+
+	for _, row := range table {
+		require.NoError(t, row.light.Update(context.Background(), row.update))
+
+		requests := server.Requests()
+		last := requests[len(requests)-1]
+		assert.Equal(t, "PUT", last.Method)
+
+		body := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(last.Body, &body))
+		assert.Equal(t, row.expected, body)
+	}
+}
+
+func TestLight_State(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	index := server.AddLight(huetest.DimmableLight("00:17:88:01:00:00:00:01-0b", "Dimmable Light"))
+	light, err := bridge.Light(context.Background(), "00:17:88:01:00:00:00:01-0b")
+	require.NoError(t, err)
+
+	server.UpdateLight(index, func(light *huetest.Light) {
+		light.State.On = true
+	})
+
+	state, err := light.State(context.Background())
+	require.NoError(t, err)
+	assert.True(t, state.Power, "change made elsewhere")
+
+	// The change made elsewhere must not be mistaken for the light's current state.
+	server.UpdateLight(index, func(light *huetest.Light) {
+		light.State.On = false
+	})
+	require.NoError(t, light.SetState(context.Background(), lucifer.LightState{Power: true, Brightness: 1}))
+
+	changed, _ := server.Light(index)
+	assert.True(t, changed.State.On, "SetState after change made elsewhere")
+}
+
+func TestLight_State_XY(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	index := server.AddLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:01-0b", "Color Light"))
+	server.UpdateLight(index, func(light *huetest.Light) {
+		light.State.On = true
+		light.State.XY = [2]float32{0.3, 0.4}
+		light.State.ColorMode = "xy"
+	})
+
+	light, err := bridge.Light(context.Background(), "00:17:88:01:00:00:00:01-0b")
+	require.NoError(t, err)
+
+	state, err := light.State(context.Background())
+	require.NoError(t, err)
+
+	x, y, _ := state.Color.XY()
+	assert.InDelta(t, 0.3, x, 0.01)
+	assert.InDelta(t, 0.4, y, 0.01)
+}
+
+func TestLight_State_Cached(t *testing.T) {
+	server := huetest.NewServer()
+	defer server.Close()
+	server.AddUser("key")
+
+	bridge, err := hue.New(hue.WithStateCache(time.Hour)).AddBridge(context.Background(), server.Addr(), "key")
+	require.NoError(t, err)
+
+	index := server.AddLight(huetest.DimmableLight("00:17:88:01:00:00:00:01-0b", "Dimmable Light"))
+	light, err := bridge.Light(context.Background(), "00:17:88:01:00:00:00:01-0b")
+	require.NoError(t, err)
+
+	server.UpdateLight(index, func(light *huetest.Light) {
+		light.State.On = true
+	})
+
+	state, err := light.State(context.Background())
+	require.NoError(t, err)
+	assert.False(t, state.Power, "before Refresh")
+
+	require.NoError(t, light.Refresh(context.Background()))
+	state, err = light.State(context.Background())
+	require.NoError(t, err)
+	assert.True(t, state.Power, "after Refresh")
+}
+
+func TestLight_SetState_Timeout(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.AddLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:01-0b", "Color Light"))
+	light, err := bridge.Light(context.Background(), "00:17:88:01:00:00:00:01-0b")
+	require.NoError(t, err)
+
+	server.SetLatency(time.Second * 5)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	started := time.Now()
+	err = light.SetState(ctx, lucifer.LightState{Power: true, Brightness: 0.5})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	assert.True(t, time.Since(started) < time.Second, "SetState returned after the deadline")
+}
+
+func TestLight_SetEffect(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	index := server.AddLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:01-0b", "Color Light"))
+	light, err := bridge.Light(context.Background(), "00:17:88:01:00:00:00:01-0b")
+	require.NoError(t, err)
+
+	require.NoError(t, light.SetEffect(context.Background(), "colorloop"))
+	changed, _ := server.Light(index)
+	assert.True(t, changed.State.On)
+	assert.Equal(t, "colorloop", changed.State.Effect)
+
+	require.NoError(t, light.SetEffect(context.Background(), ""))
+	changed, _ = server.Light(index)
+	assert.Equal(t, "none", changed.State.Effect)
+
+	require.NoError(t, light.SetEffect(context.Background(), "colorloop"))
+	require.NoError(t, light.SetState(context.Background(), lucifer.LightState{Power: true, Brightness: 1, Color: lucifer.MustParseColor("#ff0000")}))
+	changed, _ = server.Light(index)
+	assert.Equal(t, "none", changed.State.Effect, "after SetState")
+}
+
+func TestErrors(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	_, err := bridge.Group(context.Background(), "99")
+	assert.True(t, errors.Is(err, lucifer.ErrGroupNotFound), "got %v", err)
+
+	driverErr := &lucifer.DriverError{}
+	if assert.True(t, errors.As(err, &driverErr), "got %v", err) {
+		assert.Equal(t, "hue", driverErr.Driver)
+		assert.Equal(t, 3, driverErr.Type)
+		assert.Equal(t, "/groups/99", driverErr.Address)
+	}
+
+	_, err = hue.New().AddBridge(context.Background(), server.Addr(), "not-the-key")
+	assert.True(t, errors.Is(err, lucifer.ErrUnauthorized), "got %v", err)
+
+	server.Close()
+	_, err = bridge.Lights(context.Background())
+	assert.True(t, errors.Is(err, lucifer.ErrBridgeUnreachable), "got %v", err)
+}
+
+func TestDeviceInfo(t *testing.T) {
+	server, bridge := newFakeBridge(t)
+	defer server.Close()
+
+	server.AddLight(huetest.ExtendedColorLight("00:17:88:01:00:00:00:01-0b", "Color Light"))
+	index := server.AddSensor(huetest.DimmerSwitch("00:17:88:01:00:00:00:04-02-fc00", "Dimmer Switch"))
+	server.UpdateSensor(index, func(sensor *huetest.Sensor) {
+		battery := 15
+		sensor.Config.Battery = &battery
+	})
+
+	info := bridge.DeviceInfo()
+	assert.Equal(t, "BSB002", info.ModelID)
+	assert.Equal(t, "1935144040", info.FirmwareVersion)
+	assert.True(t, info.Reachable)
+
+	light, err := bridge.Light(context.Background(), "00:17:88:01:00:00:00:01-0b")
+	require.NoError(t, err)
+	lightInfo := light.DeviceInfo()
+	assert.WithinDuration(t, time.Now(), lightInfo.LastSeen, time.Minute)
+	lightInfo.LastSeen = time.Time{}
+	assert.Equal(t, lucifer.DeviceInfo{
+		Manufacturer:    "Philips",
+		ModelID:         "LCT015",
+		ProductName:     "Hue color lamp",
+		FirmwareVersion: "1.46.13_r26312",
+		Reachable:       true,
+	}, lightInfo)
+
+	sensor, err := bridge.Sensor(context.Background(), "00:17:88:01:00:00:00:04-02-fc00")
+	require.NoError(t, err)
+	info = sensor.DeviceInfo()
+	assert.Equal(t, "RWL021", info.ModelID)
+	if assert.NotNil(t, info.Battery) {
+		assert.Equal(t, 15, *info.Battery)
+	}
+	assert.True(t, info.LowBattery())
+	assert.False(t, info.LastSeen.IsZero())
+
+	server.Close()
+	_, _ = bridge.Lights(context.Background())
+	assert.False(t, bridge.DeviceInfo().Reachable, "after the bridge is gone")
+}
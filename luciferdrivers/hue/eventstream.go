@@ -0,0 +1,412 @@
+package hue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var errEventStreamUnsupported = errors.New("hue: bridge does not support the v2 event stream")
+
+// streamConnectTimeout limits how long connecting to the event stream may take.
+const streamConnectTimeout = time.Second * 10
+
+// maxStreamBackoff is the longest time subscribers fall back to polling after failing to connect.
+const maxStreamBackoff = time.Minute * 5
+
+// buttonReloadInterval is the shortest time between loading the button resources again because of
+// unknown buttons, like the ones on sensors added after connecting.
+const buttonReloadInterval = time.Second * 10
+
+// streamResource is a resource update from the event stream. Only the fields used by the driver
+// are decoded.
+type streamResource struct {
+	ID     string `json:"id"`
+	IDV1   string `json:"id_v1"`
+	Type   string `json:"type"`
+	Button *struct {
+		LastEvent string `json:"last_event"`
+	} `json:"button,omitempty"`
+
+	// ControlID is the button number on the device, looked up from the button resources.
+	ControlID int `json:"-"`
+}
+
+type streamMessage struct {
+	Type string           `json:"type"`
+	Data []streamResource `json:"data"`
+}
+
+type buttonResource struct {
+	ID       string `json:"id"`
+	IDV1     string `json:"id_v1"`
+	Metadata struct {
+		ControlID int `json:"control_id"`
+	} `json:"metadata"`
+}
+
+type subscription struct {
+	channel chan streamResource
+}
+
+// eventStream shares one connection to the bridge's v2 event stream between all subscribers.
+type eventStream struct {
+	client  *http.Client
+	baseURL string
+	key     string
+
+	mutex         sync.Mutex
+	subscriptions map[*subscription]bool
+	cancel        context.CancelFunc
+	unsupported   bool
+	controlIDs    map[string]int
+	reloadedAt    time.Time
+
+	// After failing to connect, subscribers get lastErr until retryAt.
+	lastErr error
+	retryAt time.Time
+	backoff time.Duration
+}
+
+func newEventStream(ip, key string) *eventStream {
+	return &eventStream{
+		// The client cannot have a timeout since the stream stays open, so connect limits the time
+		// until the response instead.
+		client: &http.Client{
+			Transport: &http.Transport{
+				// The bridge uses a certificate that is not signed by a public CA.
+				TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+				TLSHandshakeTimeout: streamConnectTimeout,
+			},
+		},
+		baseURL: "https://" + ip,
+		key:     key,
+	}
+}
+
+// subscribe gets a channel of resource updates until the context is cancelled. The channel is
+// never closed. It returns errEventStreamUnsupported if the bridge only supports the v1 API, and
+// the last error without trying again if connecting failed recently.
+func (stream *eventStream) subscribe(ctx context.Context) (<-chan streamResource, error) {
+	for {
+		stream.mutex.Lock()
+		if stream.unsupported {
+			stream.mutex.Unlock()
+			return nil, errEventStreamUnsupported
+		}
+		if stream.cancel != nil {
+			channel := stream.addSubscription(ctx)
+			stream.mutex.Unlock()
+			return channel, nil
+		}
+		if stream.lastErr != nil && time.Now().Before(stream.retryAt) {
+			err := stream.lastErr
+			stream.mutex.Unlock()
+			return nil, err
+		}
+		stream.mutex.Unlock()
+
+		// The bridge is contacted without holding the lock, so that a slow bridge does not block
+		// the other subscribers.
+		streamCtx, cancel := context.WithCancel(context.Background())
+		resp, controlIDs, err := stream.connectFor(ctx, streamCtx, cancel)
+		if err != nil {
+			cancel()
+
+			stream.mutex.Lock()
+			if err == errEventStreamUnsupported {
+				stream.unsupported = true
+			} else if ctx.Err() == nil {
+				stream.backoff *= 2
+				if stream.backoff == 0 {
+					stream.backoff = time.Second * 5
+				} else if stream.backoff > maxStreamBackoff {
+					stream.backoff = maxStreamBackoff
+				}
+				stream.lastErr = err
+				stream.retryAt = time.Now().Add(stream.backoff)
+			}
+			stream.mutex.Unlock()
+
+			return nil, err
+		}
+
+		stream.mutex.Lock()
+		if stream.cancel == nil {
+			stream.cancel = cancel
+			stream.subscriptions = make(map[*subscription]bool)
+			stream.controlIDs = controlIDs
+			stream.lastErr = nil
+			stream.backoff = 0
+			channel := stream.addSubscription(ctx)
+			stream.mutex.Unlock()
+
+			go stream.run(streamCtx, resp)
+
+			return channel, nil
+		}
+		stream.mutex.Unlock()
+
+		// Another subscriber connected first.
+		resp.Body.Close()
+		cancel()
+	}
+}
+
+// addSubscription adds a subscription that is removed when the context is cancelled. The mutex
+// must be held.
+func (stream *eventStream) addSubscription(ctx context.Context) <-chan streamResource {
+	sub := &subscription{channel: make(chan streamResource, 64)}
+	stream.subscriptions[sub] = true
+
+	go func() {
+		<-ctx.Done()
+
+		stream.mutex.Lock()
+		delete(stream.subscriptions, sub)
+		if len(stream.subscriptions) == 0 && stream.cancel != nil {
+			stream.cancel()
+			stream.cancel = nil
+		}
+		stream.mutex.Unlock()
+	}()
+
+	return sub.channel
+}
+
+// connectFor connects with the stream's context, but gives up if the subscriber's context is done
+// before the stream is open.
+func (stream *eventStream) connectFor(ctx, streamCtx context.Context, cancel context.CancelFunc) (*http.Response, map[string]int, error) {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+
+	resp, controlIDs, err := stream.connect(streamCtx)
+	close(stop)
+	<-stopped
+
+	if err == nil && streamCtx.Err() != nil {
+		resp.Body.Close()
+		return nil, nil, ctx.Err()
+	}
+	if err != nil && ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	return resp, controlIDs, err
+}
+
+// connect loads the button resources and opens the event stream. The response body is bound to the
+// context, while the rest is bound by streamConnectTimeout.
+func (stream *eventStream) connect(ctx context.Context) (*http.Response, map[string]int, error) {
+	controlIDs, err := stream.loadButtons(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	connectCtx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(streamConnectTimeout, cancel)
+
+	req, err := http.NewRequestWithContext(connectCtx, "GET", stream.baseURL+"/eventstream/clip/v2", nil)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	req.Header.Set("hue-application-key", stream.key)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := stream.client.Do(req)
+	if !timer.Stop() {
+		// The timeout has cancelled the request.
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		return nil, nil, fmt.Errorf("hue: event stream did not respond within %s", streamConnectTimeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, nil, errEventStreamUnsupported
+		}
+
+		return nil, nil, fmt.Errorf("hue: event stream returned %s", resp.Status)
+	}
+
+	// connectCtx is cancelled along with ctx once the stream is over.
+	return resp, controlIDs, nil
+}
+
+func (stream *eventStream) loadButtons(ctx context.Context) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(ctx, streamConnectTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", stream.baseURL+"/clip/v2/resource/button", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("hue-application-key", stream.key)
+
+	resp, err := stream.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errEventStreamUnsupported
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hue: button resources returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data []buttonResource `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	controlIDs := make(map[string]int, len(body.Data))
+	for _, button := range body.Data {
+		controlIDs[button.ID] = button.Metadata.ControlID
+	}
+
+	return controlIDs, nil
+}
+
+// run reads the stream until the context is cancelled, reconnecting if the connection is lost.
+func (stream *eventStream) run(ctx context.Context, resp *http.Response) {
+	backoff := time.Second
+
+	for {
+		stream.read(ctx, resp)
+		resp.Body.Close()
+
+		for {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+
+			newResp, controlIDs, err := stream.connect(ctx)
+			if err == nil {
+				stream.mutex.Lock()
+				stream.controlIDs = controlIDs
+				stream.mutex.Unlock()
+
+				resp = newResp
+				backoff = time.Second
+				break
+			}
+
+			if backoff < time.Second*30 {
+				backoff *= 2
+			}
+		}
+	}
+}
+
+func (stream *eventStream) read(ctx context.Context, resp *http.Response) {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	data := bytes.Buffer{}
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		switch {
+		case len(line) == 0:
+			if data.Len() > 0 {
+				stream.dispatch(ctx, data.Bytes())
+				data.Reset()
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			data.Write(bytes.TrimSpace(line[5:]))
+		}
+	}
+}
+
+func (stream *eventStream) dispatch(ctx context.Context, data []byte) {
+	var messages []streamMessage
+	err := json.Unmarshal(data, &messages)
+	if err != nil {
+		return
+	}
+
+	stream.mutex.Lock()
+	subscriptions := make([]*subscription, 0, len(stream.subscriptions))
+	for sub := range stream.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
+	controlIDs := stream.controlIDs
+	stream.mutex.Unlock()
+
+	for _, message := range messages {
+		if message.Type != "update" {
+			continue
+		}
+
+		for _, resource := range message.Data {
+			controlID, ok := controlIDs[resource.ID]
+			if !ok && resource.Button != nil {
+				controlIDs = stream.reloadButtons(ctx)
+				controlID = controlIDs[resource.ID]
+			}
+			resource.ControlID = controlID
+
+			// A subscriber that falls behind misses events rather than holding up the others.
+			for _, sub := range subscriptions {
+				select {
+				case sub.channel <- resource:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// reloadButtons loads the button resources again, unless it was done recently, and returns the
+// control IDs.
+func (stream *eventStream) reloadButtons(ctx context.Context) map[string]int {
+	stream.mutex.Lock()
+	if time.Since(stream.reloadedAt) < buttonReloadInterval {
+		controlIDs := stream.controlIDs
+		stream.mutex.Unlock()
+		return controlIDs
+	}
+	stream.reloadedAt = time.Now()
+	stream.mutex.Unlock()
+
+	controlIDs, err := stream.loadButtons(ctx)
+
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	if err == nil {
+		stream.controlIDs = controlIDs
+	}
+
+	return stream.controlIDs
+}
@@ -0,0 +1,179 @@
+package hue
+
+import (
+	"context"
+	"fmt"
+	"github.com/gissleh/lucifer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newFakeEventStreamServer(events ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/clip/v2/resource/button", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("hue-application-key") != "key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		_, _ = fmt.Fprint(w, `{"errors":[],"data":[
+			{"id":"b1","id_v1":"/sensors/5","metadata":{"control_id":1}},
+			{"id":"b4","id_v1":"/sensors/5","metadata":{"control_id":4}},
+			{"id":"c1","id_v1":"/sensors/9","metadata":{"control_id":1}}
+		]}`)
+	})
+	mux.HandleFunc("/eventstream/clip/v2", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, ": hi\n\n")
+
+		for i, event := range events {
+			_, _ = fmt.Fprintf(w, "id: 1:%d\ndata: [{\"type\":\"update\",\"data\":[%s]}]\n\n", i, event)
+		}
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	})
+
+	return httptest.NewTLSServer(mux)
+}
+
+func buttonEvent(id, event string) string {
+	return fmt.Sprintf(`{"id":"%s","id_v1":"/sensors/5","type":"button","button":{"last_event":"%s"}}`, id, event)
+}
+
+func TestSensor_ButtonEvents_Stream(t *testing.T) {
+	server := newFakeEventStreamServer(
+		buttonEvent("b1", "initial_press"),
+		buttonEvent("b1", "short_release"),
+		`{"id":"c1","id_v1":"/sensors/9","type":"button","button":{"last_event":"initial_press"}}`,
+		`{"id":"l1","id_v1":"/lights/1","type":"light","on":{"on":true}}`,
+		buttonEvent("b4", "initial_press"),
+		buttonEvent("b4", "repeat"),
+		buttonEvent("b4", "long_release"),
+		buttonEvent("b1", "short_release"),
+	)
+	defer server.Close()
+
+	stream := &eventStream{client: server.Client(), baseURL: server.URL, key: "key"}
+	sensor := &sensor{bridge: &bridge{events: stream}, index: 5, gh: sensorData{Index: 5}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	events := make([]lucifer.SensorStateButtonEvent, 0, 5)
+	for event := range sensor.ButtonEvents(ctx) {
+		events = append(events, event)
+		if len(events) == 5 {
+			cancel()
+		}
+	}
+
+	assert.Equal(t, []lucifer.SensorStateButtonEvent{
+		{Button: 1, Kind: lucifer.ButtonEventPress},
+		{Button: 4, Kind: lucifer.ButtonEventPress},
+		{Button: 4, Kind: lucifer.ButtonEventHold},
+		{Button: 4, Kind: lucifer.ButtonEventRelease},
+		{Button: 1, Kind: lucifer.ButtonEventPress},
+	}, events)
+}
+
+func TestEventStream_Subscribe_Unsupported(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	stream := &eventStream{client: server.Client(), baseURL: server.URL, key: "key"}
+
+	_, err := stream.subscribe(context.Background())
+	assert.Equal(t, errEventStreamUnsupported, err)
+	_, err = stream.subscribe(context.Background())
+	assert.Equal(t, errEventStreamUnsupported, err)
+}
+
+func TestEventStream_Dispatch_FullBuffer(t *testing.T) {
+	stream := &eventStream{subscriptions: make(map[*subscription]bool), controlIDs: map[string]int{"b1": 1}}
+	sub := &subscription{channel: make(chan streamResource, 64)}
+	stream.subscriptions[sub] = true
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			stream.dispatch(context.Background(), []byte(fmt.Sprintf(`[{"type":"update","data":[%s]}]`, buttonEvent("b1", "initial_press"))))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch blocked on a full subscriber")
+	}
+	assert.Equal(t, 64, len(sub.channel))
+}
+
+func TestSensor_ButtonEvents_NewButton(t *testing.T) {
+	loads := int32(0)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/clip/v2/resource/button", func(w http.ResponseWriter, r *http.Request) {
+		// The sensor is added after the stream is connected.
+		if atomic.AddInt32(&loads, 1) == 1 {
+			_, _ = fmt.Fprint(w, `{"errors":[],"data":[]}`)
+			return
+		}
+
+		_, _ = fmt.Fprint(w, `{"errors":[],"data":[{"id":"b3","id_v1":"/sensors/5","metadata":{"control_id":3}}]}`)
+	})
+	mux.HandleFunc("/eventstream/clip/v2", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "data: [{\"type\":\"update\",\"data\":[%s]}]\n\n", buttonEvent("b3", "initial_press"))
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	stream := &eventStream{client: server.Client(), baseURL: server.URL, key: "key"}
+	sensor := &sensor{bridge: &bridge{events: stream}, index: 5, gh: sensorData{Index: 5}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	assert.Equal(t, lucifer.SensorStateButtonEvent{Button: 3, Kind: lucifer.ButtonEventPress}, <-sensor.ButtonEvents(ctx))
+}
+
+func TestEventStream_Subscribe_Backoff(t *testing.T) {
+	requests := int32(0)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	stream := &eventStream{client: server.Client(), baseURL: server.URL, key: "key"}
+
+	_, err := stream.subscribe(context.Background())
+	assert.Error(t, err)
+	_, err = stream.subscribe(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "the second subscribe does not try again yet")
+}
+
+func TestEventStream_Subscribe_Cancel(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	stream := &eventStream{client: server.Client(), baseURL: server.URL, key: "key"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	started := time.Now()
+	_, err := stream.subscribe(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(started) < time.Second, "subscribe returned after the deadline")
+}
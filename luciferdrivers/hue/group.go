@@ -0,0 +1,88 @@
+package hue
+
+import (
+	"context"
+	hue "github.com/collinux/gohue"
+	"github.com/gissleh/lucifer"
+	"strconv"
+)
+
+type groupData struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Lights []string `json:"lights"`
+}
+
+// groupWhites is the color temperature range of the lights, which the bridge narrows for each light.
+var groupWhites = lucifer.LightCapabilities{MinKelvin: 2000, MaxKelvin: 6500}
+
+type group struct {
+	bridge *bridge
+	id     string
+	data   groupData
+}
+
+func (group *group) ID() string {
+	return group.id
+}
+
+func (group *group) Name() string {
+	return group.data.Name
+}
+
+func (group *group) Kind() lucifer.GroupKind {
+	switch group.data.Type {
+	case "Room":
+		return lucifer.GroupKindRoom
+	case "Zone":
+		return lucifer.GroupKindZone
+	case "LightGroup":
+		return lucifer.GroupKindLightGroup
+	default: // "Luminaire", "LightSource", "Entertainment"
+		return lucifer.GroupKindOther
+	}
+}
+
+func (group *group) Lights(ctx context.Context) ([]lucifer.Light, error) {
+	ghLights, err := group.bridge.lightsData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lights := make([]lucifer.Light, 0, len(group.data.Lights))
+	for _, ghLight := range ghLights {
+		for _, index := range group.data.Lights {
+			if index == strconv.Itoa(ghLight.Index) {
+				lights = append(lights, newLight(group.bridge, ghLight))
+				break
+			}
+		}
+	}
+
+	return lights, nil
+}
+
+func (group *group) SetState(ctx context.Context, state lucifer.LightState) error {
+	newState := hue.LightState{On: state.Power}
+	if state.Power {
+		newState.Bri = uint8(state.Brightness * 254)
+
+		// The bridge uses xy over ct for each light that supports it, so lights without full color
+		// show the closest white like they do in Light.SetState. Lights with color but no color
+		// temperature cannot show kelvin colors sent to the group.
+		newState.CT = ctFor(groupWhites.ClampKelvin(state.Color.Kelvin()))
+		if state.Color.K == 0 {
+			// The bridge keeps each light within its own gamut.
+			xy := xyFor(state.Color, lucifer.GamutOther)
+			newState.XY = &xy
+		}
+	}
+
+	path := "/groups/" + group.id + "/action"
+	body := lightStateBody{LightState: newState, TransitionTime: transitionTimeFor(state.Transition)}
+
+	return group.bridge.queue.Do(ctx, path, func(ctx context.Context) error {
+		_, err := group.bridge.request(ctx, "PUT", path, body, nil)
+		return err
+	})
+}
@@ -0,0 +1,837 @@
+// Package huetest provides a fake Hue bridge emulating the v1 REST API, for testing the hue driver
+// without a real bridge.
+package huetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const timeFormat = "2006-01-02T15:04:05"
+
+// Server is a fake Hue bridge. The link button needs to be pressed with PressLinkButton before a
+// user can be created, unless AddUser is used.
+type Server struct {
+	// ID is the bridge ID, which the serial number is derived from.
+	ID string
+
+	// ScanDuration is how long searches for new lights and sensors stay active.
+	ScanDuration time.Duration
+
+	server *httptest.Server
+
+	mutex          sync.Mutex
+	users          map[string]bool
+	linkButton     time.Time
+	lights         map[string]*Light
+	pendingLights  []Light
+	serialLights   map[string]Light
+	lightScan      time.Time
+	sensors        map[string]*Sensor
+	pendingSensors []Sensor
+	sensorScan     time.Time
+	groups         map[string]*Group
+	scenes         map[string]*Scene
+	nextIndex      int
+	clock          time.Time
+	requests       []Request
+	latency        time.Duration
+}
+
+// NewServer starts a fake bridge. It must be closed after use.
+func NewServer() *Server {
+	server := &Server{
+		ID:           "001788FFFE4A5B6C",
+		users:        make(map[string]bool),
+		lights:       make(map[string]*Light),
+		serialLights: make(map[string]Light),
+		sensors:      make(map[string]*Sensor),
+		groups:       make(map[string]*Group),
+		scenes:       make(map[string]*Scene),
+		clock:        time.Now().UTC().Truncate(time.Second),
+	}
+
+	server.server = httptest.NewServer(http.HandlerFunc(server.handle))
+
+	return server
+}
+
+// Close shuts down the server.
+func (server *Server) Close() {
+	server.server.Close()
+}
+
+// Addr gets the address to pass to the driver, e.g. 127.0.0.1:34567.
+func (server *Server) Addr() string {
+	return strings.TrimPrefix(server.server.URL, "http://")
+}
+
+// SerialNumber gets the serial number, which the driver uses as the bridge ID.
+func (server *Server) SerialNumber() string {
+	id := strings.ToLower(server.ID)
+	return id[:6] + id[10:]
+}
+
+// PressLinkButton allows users to be created for the next 30 seconds.
+func (server *Server) PressLinkButton() {
+	server.mutex.Lock()
+	server.linkButton = time.Now().Add(time.Second * 30)
+	server.mutex.Unlock()
+}
+
+// AddUser whitelists a key without the link button.
+func (server *Server) AddUser(key string) {
+	server.mutex.Lock()
+	server.users[key] = true
+	server.mutex.Unlock()
+}
+
+// AddLight adds the light and returns its index.
+func (server *Server) AddLight(light Light) string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	index := server.newIndex()
+	server.lights[index] = &light
+
+	return index
+}
+
+// AddPendingLight adds a light that will be found by the next search.
+func (server *Server) AddPendingLight(light Light) {
+	server.mutex.Lock()
+	server.pendingLights = append(server.pendingLights, light)
+	server.mutex.Unlock()
+}
+
+// AddSerialLight adds a light that is only found by searching for its serial number, like a light
+// that belongs to another bridge.
+func (server *Server) AddSerialLight(serial string, light Light) {
+	server.mutex.Lock()
+	server.serialLights[serial] = light
+	server.mutex.Unlock()
+}
+
+// Light gets a copy of the light with the index.
+func (server *Server) Light(index string) (Light, bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	light, ok := server.lights[index]
+	if !ok {
+		return Light{}, false
+	}
+
+	return *light, true
+}
+
+// LightIndex finds the index of the light with the unique ID.
+func (server *Server) LightIndex(uniqueID string) (string, bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for index, light := range server.lights {
+		if light.UniqueID == uniqueID {
+			return index, true
+		}
+	}
+
+	return "", false
+}
+
+// UpdateLight changes the light as if done by another app or a wall switch.
+func (server *Server) UpdateLight(index string, cb func(light *Light)) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if light, ok := server.lights[index]; ok {
+		cb(light)
+	}
+}
+
+// AddSensor adds the sensor and returns its index.
+func (server *Server) AddSensor(sensor Sensor) string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	index := server.newIndex()
+	if sensor.State.LastUpdated == "" {
+		sensor.State.LastUpdated = server.tick()
+	}
+	server.sensors[index] = &sensor
+
+	return index
+}
+
+// AddPendingSensor adds a sensor that will be found by the next search.
+func (server *Server) AddPendingSensor(sensor Sensor) {
+	server.mutex.Lock()
+	server.pendingSensors = append(server.pendingSensors, sensor)
+	server.mutex.Unlock()
+}
+
+// Sensor gets a copy of the sensor with the index.
+func (server *Server) Sensor(index string) (Sensor, bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	sensor, ok := server.sensors[index]
+	if !ok {
+		return Sensor{}, false
+	}
+
+	return *sensor, true
+}
+
+// SensorIndex finds the index of the sensor with the unique ID.
+func (server *Server) SensorIndex(uniqueID string) (string, bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for index, sensor := range server.sensors {
+		if sensor.UniqueID == uniqueID {
+			return index, true
+		}
+	}
+
+	return "", false
+}
+
+// SetButtonEvent sets the sensor's button event, e.g. 1002 for a short press on the first button.
+// The last updated time is always at least a second after the previous, since that is the
+// resolution of the API.
+func (server *Server) SetButtonEvent(index string, buttonEvent int) {
+	server.UpdateSensor(index, func(sensor *Sensor) {
+		sensor.State.ButtonEvent = &buttonEvent
+	})
+}
+
+// UpdateSensor changes the sensor's state and bumps its last updated time.
+func (server *Server) UpdateSensor(index string, cb func(sensor *Sensor)) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if sensor, ok := server.sensors[index]; ok {
+		cb(sensor)
+		sensor.State.LastUpdated = server.tick()
+	}
+}
+
+// SetLatency delays every response, to simulate a slow or hung bridge.
+func (server *Server) SetLatency(latency time.Duration) {
+	server.mutex.Lock()
+	server.latency = latency
+	server.mutex.Unlock()
+}
+
+// Requests gets the requests received so far.
+func (server *Server) Requests() []Request {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	return append([]Request(nil), server.requests...)
+}
+
+// tick advances the fake clock by at least a second. The mutex must be held.
+func (server *Server) tick() string {
+	now := time.Now().UTC().Truncate(time.Second)
+	if !now.After(server.clock) {
+		now = server.clock.Add(time.Second)
+	}
+	server.clock = now
+
+	return now.Format(timeFormat)
+}
+
+// newIndex gets the next free index. The mutex must be held.
+func (server *Server) newIndex() string {
+	server.nextIndex++
+	return strconv.Itoa(server.nextIndex)
+}
+
+func (server *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	server.mutex.Lock()
+	latency := server.latency
+	server.mutex.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.requests = append(server.requests, Request{Method: r.Method, Path: r.URL.Path, Body: body})
+
+	if r.URL.Path == "/description.xml" {
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = fmt.Fprintf(w, descriptionXML, server.Addr(), server.SerialNumber())
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "api" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var result interface{}
+	switch {
+	case len(parts) == 1 && r.Method == "POST":
+		result = server.createUser(body)
+	case len(parts) == 2 && parts[1] == "config":
+		result = server.config()
+	case !server.users[parts[1]]:
+		result = apiError(1, "/", "unauthorized user")
+	case len(parts) == 2:
+		result = server.fullState()
+	default:
+		result = server.route(r.Method, parts[2:], body)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (server *Server) route(method string, parts []string, body []byte) interface{} {
+	address := "/" + strings.Join(parts, "/")
+
+	switch parts[0] {
+	case "config":
+		return server.config()
+	case "lights":
+		return server.routeLights(method, address, parts, body)
+	case "sensors":
+		return server.routeSensors(method, address, parts, body)
+	case "groups":
+		return server.routeGroups(method, address, parts, body)
+	case "scenes":
+		return server.routeScenes(method, address, parts, body)
+	}
+
+	return apiError(4, address, fmt.Sprintf("method, %s, not available for resource, %s", method, address))
+}
+
+func (server *Server) routeLights(method, address string, parts []string, body []byte) interface{} {
+	if len(parts) == 1 {
+		switch method {
+		case "GET":
+			return server.lights
+		case "POST":
+			var data struct {
+				DeviceID []string `json:"deviceid"`
+			}
+			if len(body) > 0 && json.Unmarshal(body, &data) != nil {
+				return apiError(2, address, "body contains invalid json")
+			}
+
+			server.lightScan = time.Now().Add(server.ScanDuration)
+			for _, light := range server.pendingLights {
+				light := light
+				server.lights[server.newIndex()] = &light
+			}
+			server.pendingLights = nil
+
+			for _, serial := range data.DeviceID {
+				if light, ok := server.serialLights[serial]; ok {
+					server.lights[server.newIndex()] = &light
+					delete(server.serialLights, serial)
+				}
+			}
+
+			return success("/lights", "Searching for new devices")
+		}
+	}
+
+	if len(parts) == 2 && parts[1] == "new" {
+		return map[string]string{"lastscan": scanStatus(server.lightScan)}
+	}
+
+	light, ok := server.lights[parts[1]]
+	if !ok {
+		return notAvailable(address)
+	}
+
+	switch {
+	case len(parts) == 2 && method == "GET":
+		return light
+	case len(parts) == 2 && method == "PUT":
+		var data struct {
+			Name *string `json:"name"`
+		}
+		err := json.Unmarshal(body, &data)
+		if err != nil || data.Name == nil {
+			return apiError(2, address, "body contains invalid json")
+		}
+
+		light.Name = *data.Name
+		return success(address+"/name", light.Name)
+	case len(parts) == 2 && method == "DELETE":
+		delete(server.lights, parts[1])
+		for _, group := range server.groups {
+			group.Lights = without(group.Lights, parts[1])
+		}
+
+		return []interface{}{map[string]interface{}{"success": address + " deleted"}}
+	case len(parts) == 3 && parts[2] == "state" && method == "PUT":
+		return server.applyLightState(light, address, body)
+	}
+
+	return apiError(4, address, fmt.Sprintf("method, %s, not available for resource, %s", method, address))
+}
+
+func (server *Server) routeSensors(method, address string, parts []string, body []byte) interface{} {
+	if len(parts) == 1 {
+		switch method {
+		case "GET":
+			return server.sensors
+		case "POST":
+			server.sensorScan = time.Now().Add(server.ScanDuration)
+			for _, sensor := range server.pendingSensors {
+				sensor := sensor
+				sensor.State.LastUpdated = server.tick()
+				server.sensors[server.newIndex()] = &sensor
+			}
+			server.pendingSensors = nil
+
+			return success("/sensors", "Searching for new devices")
+		}
+	}
+
+	if len(parts) == 2 && parts[1] == "new" {
+		return map[string]string{"lastscan": scanStatus(server.sensorScan)}
+	}
+
+	sensor, ok := server.sensors[parts[1]]
+	if !ok {
+		return notAvailable(address)
+	}
+
+	switch {
+	case len(parts) == 2 && method == "GET":
+		return sensor
+	case len(parts) == 2 && method == "PUT":
+		var data struct {
+			Name *string `json:"name"`
+		}
+		err := json.Unmarshal(body, &data)
+		if err != nil || data.Name == nil {
+			return apiError(2, address, "body contains invalid json")
+		}
+
+		sensor.Name = *data.Name
+		return success(address+"/name", sensor.Name)
+	case len(parts) == 2 && method == "DELETE":
+		delete(server.sensors, parts[1])
+		return []interface{}{map[string]interface{}{"success": address + " deleted"}}
+	}
+
+	return apiError(4, address, fmt.Sprintf("method, %s, not available for resource, %s", method, address))
+}
+
+func (server *Server) routeGroups(method, address string, parts []string, body []byte) interface{} {
+	if len(parts) == 1 {
+		switch method {
+		case "GET":
+			return server.groups
+		case "POST":
+			group := &Group{}
+			err := json.Unmarshal(body, group)
+			if err != nil || group.Name == "" {
+				return apiError(2, address, "body contains invalid json")
+			}
+			if group.Type == "" {
+				group.Type = "LightGroup"
+			}
+			for _, index := range group.Lights {
+				if _, ok := server.lights[index]; !ok {
+					return apiError(7, address+"/lights", "invalid value, "+index+", for parameter, lights")
+				}
+			}
+
+			id := server.newIndex()
+			server.groups[id] = group
+
+			return []interface{}{map[string]interface{}{"success": map[string]string{"id": id}}}
+		}
+	}
+
+	var group *Group
+	if parts[1] == "0" {
+		group = &Group{Name: "Group 0", Type: "LightGroup"}
+		for index := range server.lights {
+			group.Lights = append(group.Lights, index)
+		}
+	} else if group = server.groups[parts[1]]; group == nil {
+		return notAvailable(address)
+	}
+
+	switch {
+	case len(parts) == 2 && method == "GET":
+		return group
+	case len(parts) == 2 && method == "DELETE" && parts[1] != "0":
+		delete(server.groups, parts[1])
+		return []interface{}{map[string]interface{}{"success": address + " deleted"}}
+	case len(parts) == 3 && parts[2] == "action" && method == "PUT":
+		var data struct {
+			Scene string `json:"scene"`
+		}
+		_ = json.Unmarshal(body, &data)
+		if data.Scene != "" {
+			scene, ok := server.scenes[data.Scene]
+			if !ok {
+				return apiError(7, address+"/scene", "invalid value, "+data.Scene+", for parameter, scene")
+			}
+
+			for index, state := range scene.LightStates {
+				if light, ok := server.lights[index]; ok {
+					applySceneLightState(light, state)
+				}
+			}
+
+			return success(address+"/scene", data.Scene)
+		}
+
+		fields := make(map[string]json.RawMessage)
+		if json.Unmarshal(body, &fields) != nil {
+			return apiError(2, address, "body contains invalid json")
+		}
+
+		// Like on a real bridge, the lights that can't take a change are skipped without errors.
+		for _, index := range group.Lights {
+			if light, ok := server.lights[index]; ok {
+				server.applyLightState(light, address, body)
+			}
+		}
+
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		result := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			result = append(result, success(address+"/"+key, fields[key])[0])
+		}
+
+		return result
+	}
+
+	return apiError(4, address, fmt.Sprintf("method, %s, not available for resource, %s", method, address))
+}
+
+func (server *Server) routeScenes(method, address string, parts []string, body []byte) interface{} {
+	if len(parts) == 1 {
+		switch method {
+		case "GET":
+			// Light states are only included when getting a single scene.
+			scenes := make(map[string]Scene, len(server.scenes))
+			for id, scene := range server.scenes {
+				listed := *scene
+				listed.LightStates = nil
+				scenes[id] = listed
+			}
+
+			return scenes
+		case "POST":
+			scene := &Scene{}
+			err := json.Unmarshal(body, scene)
+			if err != nil || scene.Name == "" {
+				return apiError(2, address, "body contains invalid json")
+			}
+
+			// The current states are stored when the scene is created.
+			scene.LightStates = make(map[string]SceneLightState, len(scene.Lights))
+			for _, index := range scene.Lights {
+				light, ok := server.lights[index]
+				if !ok {
+					return apiError(7, address+"/lights", "invalid value, "+index+", for parameter, lights")
+				}
+
+				scene.LightStates[index] = sceneLightStateOf(light)
+			}
+
+			id := fmt.Sprintf("scene%d", len(server.scenes)+1)
+			server.scenes[id] = scene
+
+			return []interface{}{map[string]interface{}{"success": map[string]string{"id": id}}}
+		}
+	}
+
+	scene, ok := server.scenes[parts[1]]
+	if !ok {
+		return notAvailable(address)
+	}
+
+	switch {
+	case len(parts) == 2 && method == "GET":
+		return scene
+	case len(parts) == 2 && method == "DELETE":
+		delete(server.scenes, parts[1])
+		return []interface{}{map[string]interface{}{"success": address + " deleted"}}
+	}
+
+	return apiError(4, address, fmt.Sprintf("method, %s, not available for resource, %s", method, address))
+}
+
+func (server *Server) createUser(body []byte) interface{} {
+	var data struct {
+		DeviceType string `json:"devicetype"`
+	}
+	err := json.Unmarshal(body, &data)
+	if err != nil || data.DeviceType == "" {
+		return apiError(2, "/", "body contains invalid json")
+	}
+
+	if time.Now().After(server.linkButton) {
+		return apiError(101, "", "link button not pressed")
+	}
+
+	key := fmt.Sprintf("fake-user-%d", len(server.users)+1)
+	server.users[key] = true
+
+	return []interface{}{map[string]interface{}{"success": map[string]string{"username": key}}}
+}
+
+func (server *Server) config() interface{} {
+	return map[string]interface{}{
+		"name":             "Fake Bridge",
+		"bridgeid":         server.ID,
+		"mac":              server.SerialNumber(),
+		"modelid":          "BSB002",
+		"swversion":        "1935144040",
+		"apiversion":       "1.35.0",
+		"datastoreversion": "90",
+		"linkbutton":       time.Now().Before(server.linkButton),
+	}
+}
+
+func (server *Server) fullState() interface{} {
+	return map[string]interface{}{
+		"lights":  server.lights,
+		"sensors": server.sensors,
+		"groups":  server.groups,
+		"config":  server.config(),
+	}
+}
+
+// applyLightState applies the state change in the body to the light. Like on a real bridge, a light
+// that is off only accepts changes that turn it on, and attributes the light lacks are rejected.
+func (server *Server) applyLightState(light *Light, address string, body []byte) []interface{} {
+	fields := make(map[string]json.RawMessage)
+	err := json.Unmarshal(body, &fields)
+	if err != nil {
+		return apiError(2, address, "body contains invalid json")
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if raw, ok := fields["on"]; ok {
+		err := json.Unmarshal(raw, &light.State.On)
+		if err != nil {
+			return apiError(7, address+"/on", "invalid value, "+string(raw)+", for parameter, on")
+		}
+	}
+
+	result := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		raw := fields[key]
+		fieldAddress := address + "/" + key
+		invalid := apiError(7, fieldAddress, "invalid value, "+string(raw)+", for parameter, "+key)[0]
+		unavailable := apiError(6, fieldAddress, "parameter, "+key+", not available")[0]
+
+		if key != "on" && key != "transitiontime" && !light.State.On {
+			result = append(result, apiError(201, fieldAddress, "parameter, "+key+", is not modifiable. Device is set to off.")[0])
+			continue
+		}
+
+		switch key {
+		case "on":
+		case "bri":
+			if light.Type == "On/Off plug-in unit" {
+				result = append(result, unavailable)
+				continue
+			}
+			if json.Unmarshal(raw, &light.State.Bri) != nil {
+				result = append(result, invalid)
+				continue
+			}
+		case "hue", "sat", "xy", "effect":
+			if light.Type != "Extended color light" && light.Type != "Color light" {
+				result = append(result, unavailable)
+				continue
+			}
+
+			var err error
+			switch key {
+			case "hue":
+				err = json.Unmarshal(raw, &light.State.Hue)
+				light.State.ColorMode = "hs"
+			case "sat":
+				err = json.Unmarshal(raw, &light.State.Sat)
+				light.State.ColorMode = "hs"
+			case "xy":
+				err = json.Unmarshal(raw, &light.State.XY)
+				light.State.ColorMode = "xy"
+			case "effect":
+				err = json.Unmarshal(raw, &light.State.Effect)
+			}
+			if err != nil {
+				result = append(result, invalid)
+				continue
+			}
+		case "ct":
+			if light.Type != "Extended color light" && light.Type != "Color temperature light" {
+				result = append(result, unavailable)
+				continue
+			}
+			if json.Unmarshal(raw, &light.State.CT) != nil {
+				result = append(result, invalid)
+				continue
+			}
+			light.State.ColorMode = "ct"
+		case "transitiontime":
+			var transitionTime uint16
+			if json.Unmarshal(raw, &transitionTime) != nil {
+				result = append(result, invalid)
+				continue
+			}
+		case "alert":
+			if json.Unmarshal(raw, &light.State.Alert) != nil {
+				result = append(result, invalid)
+				continue
+			}
+		default:
+			result = append(result, unavailable)
+			continue
+		}
+
+		result = append(result, success(fieldAddress, raw)[0])
+	}
+
+	return result
+}
+
+func sceneLightStateOf(light *Light) SceneLightState {
+	state := SceneLightState{On: light.State.On}
+	if light.Type == "On/Off plug-in unit" {
+		return state
+	}
+
+	bri := light.State.Bri
+	state.Bri = &bri
+
+	switch light.State.ColorMode {
+	case "ct":
+		ct := light.State.CT
+		state.CT = &ct
+	case "xy":
+		xy := light.State.XY
+		state.XY = &xy
+	case "hs":
+		hue := light.State.Hue
+		sat := light.State.Sat
+		state.Hue = &hue
+		state.Sat = &sat
+	}
+
+	return state
+}
+
+func applySceneLightState(light *Light, state SceneLightState) {
+	light.State.On = state.On
+	if state.Bri != nil {
+		light.State.Bri = *state.Bri
+	}
+	if state.CT != nil {
+		light.State.CT = *state.CT
+		light.State.ColorMode = "ct"
+	}
+	if state.Hue != nil && state.Sat != nil {
+		light.State.Hue = *state.Hue
+		light.State.Sat = *state.Sat
+		light.State.ColorMode = "hs"
+	}
+	if state.XY != nil {
+		light.State.XY = *state.XY
+		light.State.ColorMode = "xy"
+	}
+}
+
+func scanStatus(scan time.Time) string {
+	if scan.IsZero() {
+		return "none"
+	}
+	if time.Now().Before(scan) {
+		return "active"
+	}
+
+	return scan.UTC().Format(timeFormat)
+}
+
+func without(list []string, value string) []string {
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func success(address string, value interface{}) []interface{} {
+	return []interface{}{map[string]interface{}{"success": map[string]interface{}{address: value}}}
+}
+
+func notAvailable(address string) []interface{} {
+	return apiError(3, address, "resource, "+address+", not available")
+}
+
+type apiErrorBody struct {
+	Type        int    `json:"type"`
+	Address     string `json:"address"`
+	Description string `json:"description"`
+}
+
+func apiError(errorType int, address, description string) []interface{} {
+	return []interface{}{map[string]interface{}{"error": apiErrorBody{Type: errorType, Address: address, Description: description}}}
+}
+
+const descriptionXML = `<?xml version="1.0" encoding="UTF-8" ?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+<specVersion><major>1</major><minor>0</minor></specVersion>
+<URLBase>http://%s/</URLBase>
+<device>
+<deviceType>urn:schemas-upnp-org:device:Basic:1</deviceType>
+<friendlyName>Fake Bridge</friendlyName>
+<manufacturer>Signify</manufacturer>
+<manufacturerURL>http://www.philips-hue.com</manufacturerURL>
+<modelDescription>Philips hue Personal Wireless Lighting</modelDescription>
+<modelName>Philips hue bridge 2015</modelName>
+<modelNumber>BSB002</modelNumber>
+<modelURL>http://www.philips-hue.com</modelURL>
+<serialNumber>%s</serialNumber>
+<UDN>uuid:2f402f80-da50-11e1-9b23-001788000000</UDN>
+</device>
+</root>
+`
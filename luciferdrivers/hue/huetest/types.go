@@ -0,0 +1,285 @@
+package huetest
+
+// LightState is the state of a fake light, as returned by the API.
+type LightState struct {
+	On        bool       `json:"on"`
+	Bri       uint8      `json:"bri"`
+	Hue       uint16     `json:"hue"`
+	Sat       uint8      `json:"sat"`
+	Effect    string     `json:"effect"`
+	XY        [2]float32 `json:"xy"`
+	CT        int        `json:"ct"`
+	Alert     string     `json:"alert"`
+	ColorMode string     `json:"colormode"`
+	Reachable bool       `json:"reachable"`
+}
+
+// Light is a fake light. The fields follow the API.
+type Light struct {
+	State            LightState `json:"state"`
+	Type             string     `json:"type"`
+	Name             string     `json:"name"`
+	ModelID          string     `json:"modelid"`
+	ManufacturerName string     `json:"manufacturername"`
+	ProductName      string     `json:"productname,omitempty"`
+	UniqueID         string     `json:"uniqueid"`
+	SWVersion        string     `json:"swversion"`
+}
+
+// SensorState is the state of a fake sensor. The fields that don't apply to the type should be nil.
+type SensorState struct {
+	Daylight    *bool  `json:"daylight,omitempty"`
+	Dark        *bool  `json:"dark,omitempty"`
+	ButtonEvent *int   `json:"buttonevent,omitempty"`
+	Presence    *bool  `json:"presence,omitempty"`
+	Temperature *int   `json:"temperature,omitempty"`
+	LightLevel  *int   `json:"lightlevel,omitempty"`
+	Open        *bool  `json:"open,omitempty"`
+	Humidity    *int   `json:"humidity,omitempty"`
+	LastUpdated string `json:"lastupdated"`
+}
+
+// SensorConfig is the config of a fake sensor.
+type SensorConfig struct {
+	On        bool `json:"on"`
+	Reachable bool `json:"reachable"`
+	Battery   *int `json:"battery,omitempty"`
+}
+
+// Sensor is a fake sensor. The fields follow the API.
+type Sensor struct {
+	State            SensorState  `json:"state"`
+	Config           SensorConfig `json:"config"`
+	Type             string       `json:"type"`
+	Name             string       `json:"name"`
+	ModelID          string       `json:"modelid"`
+	ManufacturerName string       `json:"manufacturername"`
+	ProductName      string       `json:"productname,omitempty"`
+	UniqueID         string       `json:"uniqueid,omitempty"`
+	SWVersion        string       `json:"swversion"`
+}
+
+// Group is a fake group. The fields follow the API.
+type Group struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Class  string   `json:"class,omitempty"`
+	Lights []string `json:"lights"`
+}
+
+// SceneLightState is a light's state in a scene.
+type SceneLightState struct {
+	On  bool        `json:"on"`
+	Bri *uint8      `json:"bri,omitempty"`
+	Hue *uint16     `json:"hue,omitempty"`
+	Sat *uint8      `json:"sat,omitempty"`
+	XY  *[2]float32 `json:"xy,omitempty"`
+	CT  *int        `json:"ct,omitempty"`
+}
+
+// Scene is a fake scene. The fields follow the API.
+type Scene struct {
+	Name        string                     `json:"name"`
+	Lights      []string                   `json:"lights"`
+	Recycle     bool                       `json:"recycle"`
+	LightStates map[string]SceneLightState `json:"lightstates,omitempty"`
+}
+
+// Request is a request received by the fake bridge.
+type Request struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// ExtendedColorLight creates a light of the type used by Hue color bulbs.
+func ExtendedColorLight(uniqueID, name string) Light {
+	return Light{
+		State:            LightState{Bri: 254, Hue: 8418, Sat: 140, Effect: "none", CT: 366, Alert: "none", ColorMode: "ct", Reachable: true},
+		Type:             "Extended color light",
+		Name:             name,
+		ModelID:          "LCT015",
+		ManufacturerName: "Philips",
+		ProductName:      "Hue color lamp",
+		UniqueID:         uniqueID,
+		SWVersion:        "1.46.13_r26312",
+	}
+}
+
+// ColorLight creates a light of the type used by LivingColors lamps, which have no color temperature.
+func ColorLight(uniqueID, name string) Light {
+	return Light{
+		State:            LightState{Bri: 254, Hue: 8418, Sat: 140, Effect: "none", Alert: "none", ColorMode: "xy", Reachable: true},
+		Type:             "Color light",
+		Name:             name,
+		ModelID:          "LLC010",
+		ManufacturerName: "Philips",
+		ProductName:      "LivingColors",
+		UniqueID:         uniqueID,
+		SWVersion:        "5.23.1.13452",
+	}
+}
+
+// ColorTemperatureLight creates a light of the type used by Hue white ambiance bulbs.
+func ColorTemperatureLight(uniqueID, name string) Light {
+	return Light{
+		State:            LightState{Bri: 254, Alert: "none", CT: 366, ColorMode: "ct", Reachable: true},
+		Type:             "Color temperature light",
+		Name:             name,
+		ModelID:          "LTW001",
+		ManufacturerName: "Philips",
+		ProductName:      "Hue ambiance lamp",
+		UniqueID:         uniqueID,
+		SWVersion:        "1.46.13_r26312",
+	}
+}
+
+// DimmableLight creates a light of the type used by Hue white bulbs.
+func DimmableLight(uniqueID, name string) Light {
+	return Light{
+		State:            LightState{Bri: 254, Alert: "none", Reachable: true},
+		Type:             "Dimmable light",
+		Name:             name,
+		ModelID:          "LWB010",
+		ManufacturerName: "Philips",
+		ProductName:      "Hue white lamp",
+		UniqueID:         uniqueID,
+		SWVersion:        "1.46.13_r26312",
+	}
+}
+
+// DimmerSwitch creates a sensor of the type used by the Hue dimmer switch.
+func DimmerSwitch(uniqueID, name string) Sensor {
+	buttonEvent := 1002
+	battery := 100
+
+	return Sensor{
+		State:            SensorState{ButtonEvent: &buttonEvent},
+		Config:           SensorConfig{On: true, Reachable: true, Battery: &battery},
+		Type:             "ZLLSwitch",
+		Name:             name,
+		ModelID:          "RWL021",
+		ManufacturerName: "Philips",
+		ProductName:      "Hue dimmer switch",
+		UniqueID:         uniqueID,
+		SWVersion:        "6.1.1.28573",
+	}
+}
+
+// TapSwitch creates a sensor of the type used by the Hue Tap, which has no battery.
+func TapSwitch(uniqueID, name string) Sensor {
+	buttonEvent := 34
+
+	return Sensor{
+		State:            SensorState{ButtonEvent: &buttonEvent},
+		Config:           SensorConfig{On: true},
+		Type:             "ZGPSwitch",
+		Name:             name,
+		ModelID:          "ZGPSWITCH",
+		ManufacturerName: "Philips",
+		ProductName:      "Hue tap switch",
+		UniqueID:         uniqueID,
+	}
+}
+
+// DaylightSensor creates the bridge's built-in daylight sensor.
+func DaylightSensor() Sensor {
+	daylight := true
+
+	return Sensor{
+		State:            SensorState{Daylight: &daylight},
+		Config:           SensorConfig{On: true, Reachable: true},
+		Type:             "Daylight",
+		Name:             "Daylight",
+		ModelID:          "PHDL00",
+		ManufacturerName: "Philips",
+		SWVersion:        "1.0",
+	}
+}
+
+// PresenceSensor creates the motion part of a Hue motion sensor. The uniqueID should end in -0406.
+func PresenceSensor(uniqueID, name string) Sensor {
+	presence := false
+	battery := 100
+
+	return Sensor{
+		State:            SensorState{Presence: &presence},
+		Config:           SensorConfig{On: true, Reachable: true, Battery: &battery},
+		Type:             "ZLLPresence",
+		Name:             name,
+		ModelID:          "SML001",
+		ManufacturerName: "Philips",
+		ProductName:      "Hue motion sensor",
+		UniqueID:         uniqueID,
+		SWVersion:        "6.1.1.27575",
+	}
+}
+
+// TemperatureSensor creates the temperature part of a Hue motion sensor. The uniqueID should end in
+// -0402. The temperature is in hundredths of degrees Celsius.
+func TemperatureSensor(uniqueID, name string, temperature int) Sensor {
+	battery := 100
+
+	return Sensor{
+		State:            SensorState{Temperature: &temperature},
+		Config:           SensorConfig{On: true, Reachable: true, Battery: &battery},
+		Type:             "ZLLTemperature",
+		Name:             name,
+		ModelID:          "SML001",
+		ManufacturerName: "Philips",
+		ProductName:      "Hue motion sensor",
+		UniqueID:         uniqueID,
+		SWVersion:        "6.1.1.27575",
+	}
+}
+
+// LightLevelSensor creates the light level part of a Hue motion sensor. The uniqueID should end in
+// -0400. The light level is 10000 log10(lux) + 1.
+func LightLevelSensor(uniqueID, name string, lightLevel int) Sensor {
+	daylight := lightLevel >= 16000
+	dark := lightLevel < 12000
+	battery := 100
+
+	return Sensor{
+		State:            SensorState{LightLevel: &lightLevel, Daylight: &daylight, Dark: &dark},
+		Config:           SensorConfig{On: true, Reachable: true, Battery: &battery},
+		Type:             "ZLLLightLevel",
+		Name:             name,
+		ModelID:          "SML001",
+		ManufacturerName: "Philips",
+		ProductName:      "Hue motion sensor",
+		UniqueID:         uniqueID,
+		SWVersion:        "6.1.1.27575",
+	}
+}
+
+// OpenCloseSensor creates a contact sensor of the kind that apps add to the bridge.
+func OpenCloseSensor(uniqueID, name string) Sensor {
+	open := false
+
+	return Sensor{
+		State:            SensorState{Open: &open},
+		Config:           SensorConfig{On: true, Reachable: true},
+		Type:             "CLIPOpenClose",
+		Name:             name,
+		ModelID:          "OpenClose",
+		ManufacturerName: "huetest",
+		UniqueID:         uniqueID,
+		SWVersion:        "1.0",
+	}
+}
+
+// HumiditySensor creates a humidity sensor of the kind that apps add to the bridge. The humidity is
+// in hundredths of percent.
+func HumiditySensor(uniqueID, name string, humidity int) Sensor {
+	return Sensor{
+		State:            SensorState{Humidity: &humidity},
+		Config:           SensorConfig{On: true, Reachable: true},
+		Type:             "CLIPHumidity",
+		Name:             name,
+		ModelID:          "Humidity",
+		ManufacturerName: "huetest",
+		UniqueID:         uniqueID,
+		SWVersion:        "1.0",
+	}
+}
@@ -1,31 +1,136 @@
 package hue
 
 import (
+	"context"
+	"encoding/json"
 	hue "github.com/collinux/gohue"
 	"github.com/gissleh/lucifer"
+	"math"
+	"strconv"
+	"sync"
+	"time"
 )
 
 type light struct {
-	gh hue.Light
+	bridge *bridge
+	index  int
+
+	mutex   sync.Mutex
+	gh      lightData
+	fetched time.Time
+}
+
+func newLight(bridge *bridge, gh lightData) *light {
+	return &light{bridge: bridge, index: gh.Index, gh: gh, fetched: time.Now()}
+}
+
+// lightStateBody adds a numeric transition time to gohue's state, which would send it as a string.
+type lightStateBody struct {
+	hue.LightState
+	TransitionTime *uint16 `json:"transitiontime,omitempty"`
+}
+
+// lightUpdateBody only has the attributes that should change.
+type lightUpdateBody struct {
+	On             *bool       `json:"on,omitempty"`
+	Bri            *uint8      `json:"bri,omitempty"`
+	XY             *[2]float32 `json:"xy,omitempty"`
+	CT             *uint16     `json:"ct,omitempty"`
+	TransitionTime *uint16     `json:"transitiontime,omitempty"`
 }
 
 func (light *light) ID() string {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
 	return light.gh.UniqueID
 }
 
 func (light *light) Name() string {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
 	return light.gh.Name
 }
 
-func (light *light) SetName(name string) error {
-	return light.gh.SetState(hue.LightState{
-		Name: name,
+func (light *light) SetName(ctx context.Context, name string) error {
+	return light.bridge.queue.Do(ctx, light.path()+"/name", func(ctx context.Context) error {
+		return light.setName(ctx, name)
+	})
+}
+
+func (light *light) setName(ctx context.Context, name string) error {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
+	_, err := light.bridge.request(ctx, "PUT", light.path(), map[string]string{"name": name}, nil)
+	if err != nil {
+		return err
+	}
+
+	light.gh.Name = name
+
+	return nil
+}
+
+func (light *light) DeviceInfo() lucifer.DeviceInfo {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
+	info := lucifer.DeviceInfo{
+		Manufacturer:    light.gh.ManufacturerName,
+		ModelID:         light.gh.ModelID,
+		ProductName:     light.gh.ProductName,
+		FirmwareVersion: light.gh.SWVersion,
+		Reachable:       light.gh.State.Reachable,
+	}
+	if info.Reachable {
+		// The bridge only reports the light as reachable if it heard from it recently.
+		info.LastSeen = light.fetched
+	}
+
+	return info
+}
+
+func (light *light) Capabilities() lucifer.LightCapabilities {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
+	return capabilitiesFor(light.gh.Type, light.gh.ModelID)
+}
+
+// Refresh fetches the light's state from the bridge.
+func (light *light) Refresh(ctx context.Context) error {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
+	return light.refresh(ctx)
+}
+
+// SetState changes the parts of the state that differ from the light's current state. A state that
+// is still waiting in the bridge's write queue is replaced by the next one.
+func (light *light) SetState(ctx context.Context, state lucifer.LightState) error {
+	return light.bridge.queue.Do(ctx, light.path()+"/state", func(ctx context.Context) error {
+		return light.setState(ctx, state)
 	})
 }
 
-func (light *light) SetState(state lucifer.LightState) error {
-	h, s, _ := state.Color.HSV()
+// RateLimiter gets the limiter of the bridge's write queue, so that effects do not wait for it twice.
+func (light *light) RateLimiter() *lucifer.RateLimiter {
+	return light.bridge.queue.Limiter()
+}
+
+func (light *light) setState(ctx context.Context, state lucifer.LightState) error {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
+	err := light.refreshIfStale(ctx)
+	if err != nil {
+		return err
+	}
+
 	ghState := light.gh.State
+	capabilities := capabilitiesFor(light.gh.Type, light.gh.ModelID)
 	newState := hue.LightState{}
 	changed := false
 
@@ -34,34 +139,25 @@ func (light *light) SetState(state lucifer.LightState) error {
 	}
 	newState.On = state.Power
 
-	brightness := uint8(state.Brightness * 254)
-	if ghState.Bri != brightness {
-		changed = true
-	}
-	newState.Bri = brightness
-
-	if state.Color.K == 0 {
-		h16 := uint16(h * (65536 / 360))
-		if h16 == 0 {
-			h16 = 1
-		}
-		if h16 != ghState.Hue {
+	if capabilities.Dimmable() {
+		brightness := uint8(state.Brightness * 254)
+		if ghState.Bri != brightness {
 			changed = true
 		}
-		newState.Hue = h16
-
-		s8 := uint8(s * 254)
-		if s8 == 0 {
-			s8 = 1
-		}
+		newState.Bri = brightness
+	}
 
-		if s8 != ghState.Saturation && ghState.ColorMode != "hs" {
+	// Lights without color temperature show white colors as xy instead.
+	if capabilities.Color && (state.Color.K == 0 || !capabilities.ColorTemperature) {
+		xy := xyFor(state.Color, capabilities.Gamut)
+		if ghState.ColorMode != "xy" || math.Abs(float64(xy[0]-ghState.XY[0])) > 0.002 || math.Abs(float64(xy[1]-ghState.XY[1])) > 0.002 {
 			changed = true
 		}
 
-		newState.Sat = s8
-	} else {
-		newCT := uint16(1000000 / state.Color.K)
+		newState.XY = &xy
+	} else if capabilities.ColorTemperature {
+		// Lights without full color show the closest white.
+		newCT := ctFor(capabilities.ClampKelvin(state.Color.Kelvin()))
 		diff := int(newCT) - ghState.CT
 
 		if diff < -75 || diff > 75 || ghState.ColorMode != "ct" {
@@ -70,8 +166,8 @@ func (light *light) SetState(state lucifer.LightState) error {
 		}
 	}
 
-	// Effects are not supported
-	if ghState.Effect != "none" {
+	// Setting the state stops native effects.
+	if len(capabilities.Effects) > 0 && ghState.Effect != "none" {
 		newState.Effect = "none"
 		changed = true
 	}
@@ -80,21 +176,132 @@ func (light *light) SetState(state lucifer.LightState) error {
 		return nil
 	}
 
+	transitionTime := transitionTimeFor(state.Transition)
+
 	if newState.On == false {
-		return light.gh.SetState(hue.LightState{On: false})
+		return light.putState(ctx, lightStateBody{TransitionTime: transitionTime})
 	}
 
-	return light.gh.SetState(newState)
+	return light.putState(ctx, lightStateBody{LightState: newState, TransitionTime: transitionTime})
 }
 
-func (light *light) State() (lucifer.LightState, error) {
-	ghState := light.gh.State
+// Update sends only the attributes in the update, as far as the capabilities allow. Other than
+// power, the attributes cannot be changed while the light is off.
+func (light *light) Update(ctx context.Context, update lucifer.LightUpdate) error {
+	return light.bridge.queue.Do(ctx, "", func(ctx context.Context) error {
+		return light.update(ctx, update)
+	})
+}
+
+func (light *light) update(ctx context.Context, update lucifer.LightUpdate) error {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
+	capabilities := capabilitiesFor(light.gh.Type, light.gh.ModelID)
+	body := lightUpdateBody{
+		On:             update.Power,
+		TransitionTime: transitionTimeFor(update.Transition),
+	}
+
+	if update.Power == nil || *update.Power {
+		if update.Brightness != nil && capabilities.Dimmable() {
+			brightness := uint8(*update.Brightness * 254)
+			body.Bri = &brightness
+		}
+
+		kelvin := 0
+		if update.Kelvin != nil {
+			kelvin = *update.Kelvin
+		} else if update.Color != nil && (update.Color.K != 0 || !capabilities.Color) {
+			// Lights without full color show the closest white.
+			kelvin = update.Color.Kelvin()
+		}
+
+		if kelvin != 0 && capabilities.ColorTemperature {
+			ct := ctFor(capabilities.ClampKelvin(kelvin))
+			body.CT = &ct
+		} else if capabilities.Color && (kelvin != 0 || update.Color != nil) {
+			// Lights without color temperature show white colors as xy instead.
+			color := lucifer.Color{}
+			if update.Kelvin != nil {
+				color.SetKelvin(kelvin)
+			} else {
+				color = *update.Color
+			}
+
+			xy := xyFor(color, capabilities.Gamut)
+			body.XY = &xy
+		}
+	}
+
+	if body.On == nil && body.Bri == nil && body.XY == nil && body.CT == nil {
+		return nil
+	}
+
+	return light.putState(ctx, body)
+}
+
+// SetEffect starts or stops the native effect. Effects turn the light on, since they cannot run
+// while it is off.
+func (light *light) SetEffect(ctx context.Context, name string) error {
+	return light.bridge.queue.Do(ctx, "", func(ctx context.Context) error {
+		return light.setEffect(ctx, name)
+	})
+}
+
+func (light *light) setEffect(ctx context.Context, name string) error {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
+	capabilities := capabilitiesFor(light.gh.Type, light.gh.ModelID)
+	if name == "" {
+		if len(capabilities.Effects) == 0 {
+			return nil
+		}
+
+		err := light.refreshIfStale(ctx)
+		if err != nil {
+			return err
+		}
+		if !light.gh.State.On || light.gh.State.Effect == "none" {
+			return nil
+		}
+
+		return light.putState(ctx, map[string]interface{}{"effect": "none"})
+	}
+	if !capabilities.HasEffect(name) {
+		return lucifer.ErrUnsupportedOperation
+	}
+
+	return light.putState(ctx, map[string]interface{}{"on": true, "effect": name})
+}
+
+// State gets the light's state, which is fetched from the bridge unless the cached state is recent
+// enough. See WithStateCache.
+func (light *light) State(ctx context.Context) (lucifer.LightState, error) {
+	light.mutex.Lock()
+	defer light.mutex.Unlock()
+
+	err := light.refreshIfStale(ctx)
+	if err != nil {
+		return lucifer.LightState{}, err
+	}
+
+	return lightStateOf(light.gh), nil
+}
+
+// lightStateOf converts the light's state as it was when fetched.
+func lightStateOf(gh lightData) lucifer.LightState {
+	ghState := gh.State
 
 	color := lucifer.Color{}
 
-	if ghState.ColorMode == "ct" {
+	switch ghState.ColorMode {
+	case "ct":
 		color.SetKelvin(1000000 / ghState.CT)
-	} else /* "hs" or "xy" */ {
+	case "xy":
+		color.SetXY(float64(ghState.XY[0]), float64(ghState.XY[1]), float64(ghState.Bri)/254)
+	default: // "hs", or no color
 		color.SetHSV(
 			float64(ghState.Hue)/(65536/360),
 			float64(ghState.Saturation)/254,
@@ -106,9 +313,101 @@ func (light *light) State() (lucifer.LightState, error) {
 		Power:      ghState.On,
 		Brightness: float64(ghState.Bri) / 254,
 		Color:      color,
-	}, nil
+	}
+}
+
+// putState sends the state change, and applies it to the last fetched state instead of fetching it
+// again. The mutex must be held.
+func (light *light) putState(ctx context.Context, body interface{}) error {
+	_, err := light.bridge.request(ctx, "PUT", light.path()+"/state", body, nil)
+	if err != nil {
+		return err
+	}
+
+	// The body has the same names as the state, so the attributes that were sent replace the old.
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	var sent struct {
+		XY *[2]float32 `json:"xy"`
+		CT *int        `json:"ct"`
+	}
+	err = json.Unmarshal(data, &sent)
+	if err == nil {
+		err = json.Unmarshal(data, &light.gh.State)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case sent.XY != nil:
+		light.gh.State.ColorMode = "xy"
+	case sent.CT != nil:
+		light.gh.State.ColorMode = "ct"
+	}
+
+	return nil
+}
+
+// refresh fetches the light. The mutex must be held.
+func (light *light) refresh(ctx context.Context) error {
+	ghLight, err := light.bridge.lightData(ctx, light.index)
+	if err != nil {
+		return err
+	}
+
+	light.gh = ghLight
+	light.fetched = time.Now()
+
+	return nil
+}
+
+// refreshIfStale fetches the light unless it was fetched within the bridge's state TTL. The mutex
+// must be held.
+func (light *light) refreshIfStale(ctx context.Context) error {
+	if light.bridge.stateTTL > 0 && time.Since(light.fetched) < light.bridge.stateTTL {
+		return nil
+	}
+
+	return light.refresh(ctx)
+}
+
+func (light *light) Forget(ctx context.Context) error {
+	_, err := light.bridge.write(ctx, "DELETE", light.path(), nil)
+	return err
+}
+
+// path gets the light's path in the API.
+func (light *light) path() string {
+	return "/lights/" + strconv.Itoa(light.index)
+}
+
+// transitionTimeFor converts the duration to the bridge's 100ms units, or nil for the default.
+func transitionTimeFor(duration time.Duration) *uint16 {
+	if duration <= 0 {
+		return nil
+	}
+
+	units := duration / (time.Millisecond * 100)
+	if units > 65535 {
+		units = 65535
+	}
+
+	transitionTime := uint16(units)
+	return &transitionTime
+}
+
+// xyFor converts the color to xy coordinates inside the gamut, with the bridge's precision.
+func xyFor(color lucifer.Color, gamut lucifer.ColorGamut) [2]float32 {
+	x, y, _ := color.XY()
+	x, y = gamut.ClampXY(x, y)
+
+	return [2]float32{float32(math.Round(x*10000) / 10000), float32(math.Round(y*10000) / 10000)}
 }
 
-func (light *light) Forget() error {
-	return light.gh.Delete()
+// ctFor converts the color temperature to mireds.
+func ctFor(kelvin int) uint16 {
+	return uint16(1000000 / kelvin)
 }
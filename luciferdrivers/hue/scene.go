@@ -0,0 +1,38 @@
+package hue
+
+import "github.com/gissleh/lucifer"
+
+type sceneData struct {
+	Name        string                    `json:"name"`
+	Lights      []string                  `json:"lights"`
+	Recycle     bool                      `json:"recycle"`
+	LightStates map[string]sceneLightData `json:"lightstates,omitempty"`
+}
+
+type sceneLightData struct {
+	On  bool        `json:"on"`
+	Bri *uint8      `json:"bri,omitempty"`
+	Hue *uint16     `json:"hue,omitempty"`
+	Sat *uint8      `json:"sat,omitempty"`
+	CT  *int        `json:"ct,omitempty"`
+	XY  *[2]float32 `json:"xy,omitempty"`
+}
+
+func (data *sceneLightData) lightState() lucifer.LightState {
+	state := lucifer.LightState{Power: data.On}
+	if data.Bri != nil {
+		state.Brightness = float64(*data.Bri) / 254
+	}
+
+	if data.CT != nil && *data.CT > 0 {
+		state.Color.SetKelvin(1000000 / *data.CT)
+	} else if data.XY != nil {
+		state.Color.SetXY(float64(data.XY[0]), float64(data.XY[1]), 1)
+	} else if data.Hue != nil && data.Sat != nil {
+		state.Color.SetHSV(float64(*data.Hue)/(65536/360), float64(*data.Sat)/254, 1)
+	} else {
+		state.Color.SetHSV(0, 0, 1)
+	}
+
+	return state
+}
@@ -0,0 +1,235 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"github.com/gissleh/lucifer"
+	"time"
+)
+
+// scanWindow is about how long the bridge searches for new devices. It is only used to estimate the
+// progress, since the bridge reports when the search is over.
+const scanWindow = time.Second * 40
+
+// maxSerials is the number of serial numbers the bridge can search for at once.
+const maxSerials = 10
+
+// scanPollInterval is how often the bridge is asked for new devices during a search.
+var scanPollInterval = time.Second
+
+// SearchDevices searches for new devices for as long as the bridge's scan lasts. Serial numbers are
+// only supported for lights.
+func (bridge *bridge) SearchDevices(ctx context.Context, search lucifer.DeviceSearch) <-chan lucifer.SearchEvent {
+	channel := make(chan lucifer.SearchEvent, 16)
+	go bridge.search(ctx, search, channel)
+
+	return channel
+}
+
+func (bridge *bridge) search(ctx context.Context, search lucifer.DeviceSearch, channel chan<- lucifer.SearchEvent) {
+	sentDone := false
+	defer func() {
+		// The search is cut short if the context is done. The done event is only sent if there is
+		// room for it, since nobody may be reading anymore.
+		if !sentDone && ctx.Err() != nil {
+			select {
+			case channel <- lucifer.SearchEvent{Kind: lucifer.SearchEventDone, Err: ctx.Err()}:
+			default:
+			}
+		}
+
+		close(channel)
+	}()
+
+	send := func(event lucifer.SearchEvent) bool {
+		select {
+		case channel <- event:
+			sentDone = event.Kind == lucifer.SearchEventDone
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	fail := func(err error) {
+		send(lucifer.SearchEvent{Kind: lucifer.SearchEventDone, Err: err})
+	}
+
+	if len(search.Serials) > maxSerials {
+		fail(errors.New("hue: at most 10 serial numbers can be searched for at once"))
+		return
+	}
+
+	seenLights := make(map[string]bool)
+	seenSensors := make(map[string]bool)
+
+	// The devices already on the bridge are not new.
+	if search.IncludesLights() {
+		_, err := bridge.newLights(ctx, seenLights)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		var body interface{}
+		if len(search.Serials) > 0 {
+			body = map[string][]string{"deviceid": search.Serials}
+		}
+
+		_, err = bridge.request(ctx, "POST", "/lights", body, nil)
+		if err != nil {
+			fail(err)
+			return
+		}
+	}
+	if search.IncludesSensors() {
+		_, err := bridge.newSensors(ctx, seenSensors)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		_, err = bridge.request(ctx, "POST", "/sensors", nil, nil)
+		if err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	started := time.Now()
+	for {
+		// The scan status is checked first, so that devices found at the end are listed below.
+		active := false
+		if search.IncludesLights() {
+			lightsActive, err := bridge.scanActive(ctx, "/lights/new")
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			lights, err := bridge.newLights(ctx, seenLights)
+			if err != nil {
+				fail(err)
+				return
+			}
+			for _, light := range lights {
+				if !send(lucifer.SearchEvent{Kind: lucifer.SearchEventLightFound, Light: light}) {
+					return
+				}
+			}
+
+			active = active || lightsActive
+		}
+		if search.IncludesSensors() {
+			sensorsActive, err := bridge.scanActive(ctx, "/sensors/new")
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			sensors, err := bridge.newSensors(ctx, seenSensors)
+			if err != nil {
+				fail(err)
+				return
+			}
+			for _, sensor := range sensors {
+				if !send(lucifer.SearchEvent{Kind: lucifer.SearchEventSensorFound, Sensor: sensor}) {
+					return
+				}
+			}
+
+			active = active || sensorsActive
+		}
+
+		if !active {
+			send(lucifer.SearchEvent{Kind: lucifer.SearchEventDone, Progress: 1})
+			return
+		}
+
+		progress := float64(time.Since(started)) / float64(scanWindow)
+		if progress > 0.99 {
+			progress = 0.99
+		}
+		if !send(lucifer.SearchEvent{Kind: lucifer.SearchEventProgress, Progress: progress}) {
+			return
+		}
+
+		select {
+		case <-time.After(scanPollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// discover runs the search to the end, and returns the devices found.
+func (bridge *bridge) discover(ctx context.Context, search lucifer.DeviceSearch) ([]lucifer.Light, []lucifer.Sensor, error) {
+	lights := make([]lucifer.Light, 0, 8)
+	sensors := make([]lucifer.Sensor, 0, 8)
+
+	for event := range bridge.SearchDevices(ctx, search) {
+		switch event.Kind {
+		case lucifer.SearchEventLightFound:
+			lights = append(lights, event.Light)
+		case lucifer.SearchEventSensorFound:
+			sensors = append(sensors, event.Sensor)
+		case lucifer.SearchEventDone:
+			if event.Err != nil {
+				return nil, nil, event.Err
+			}
+
+			return lights, sensors, nil
+		}
+	}
+
+	// The channel only closes without a done event if the context is done and the buffer was full.
+	return nil, nil, ctx.Err()
+}
+
+// newLights lists the lights that are not yet seen, and marks them as seen.
+func (bridge *bridge) newLights(ctx context.Context, seen map[string]bool) ([]lucifer.Light, error) {
+	ghLights, err := bridge.lightsData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lights := make([]lucifer.Light, 0, 4)
+	for _, ghLight := range ghLights {
+		if !seen[ghLight.UniqueID] {
+			seen[ghLight.UniqueID] = true
+			lights = append(lights, newLight(bridge, ghLight))
+		}
+	}
+
+	return lights, nil
+}
+
+// newSensors lists the sensors that are not yet seen, and marks them as seen.
+func (bridge *bridge) newSensors(ctx context.Context, seen map[string]bool) ([]lucifer.Sensor, error) {
+	ghSensors, err := bridge.sensorsData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sensors := make([]lucifer.Sensor, 0, 4)
+	for _, ghSensor := range ghSensors {
+		if ghSensor.UniqueID != "" && !seen[ghSensor.UniqueID] {
+			seen[ghSensor.UniqueID] = true
+			sensors = append(sensors, newSensor(bridge, ghSensor))
+		}
+	}
+
+	return sensors, nil
+}
+
+// scanActive gets whether the search for new devices at the path is still running.
+func (bridge *bridge) scanActive(ctx context.Context, path string) (bool, error) {
+	var result struct {
+		LastScan string `json:"lastscan"`
+	}
+	_, err := bridge.request(ctx, "GET", path, nil, &result)
+	if err != nil {
+		return false, err
+	}
+
+	return result.LastScan == "active", nil
+}
@@ -2,146 +2,370 @@ package hue
 
 import (
 	"context"
-	hue "github.com/collinux/gohue"
+	"fmt"
 	"github.com/gissleh/lucifer"
+	"math"
+	"strconv"
+	"sync"
 	"time"
 )
 
+// tapButtons is the button number of each button event code of the Hue Tap.
+var tapButtons = map[uint16]int{34: 1, 16: 2, 17: 3, 18: 4}
+
 type sensor struct {
-	gh hue.Sensor
+	bridge *bridge
+	index  int
+
+	mutex sync.Mutex
+	gh    sensorData
 
 	prevButtonTime  time.Time
 	prevButtonState uint16
 }
 
+func newSensor(bridge *bridge, gh sensorData) *sensor {
+	sensor := &sensor{bridge: bridge, index: gh.Index, gh: gh}
+	if gh.State.LastUpdated.Time != nil {
+		// Button events from before the sensor was listed should not be reported.
+		sensor.prevButtonTime = *gh.State.LastUpdated.Time
+		sensor.prevButtonState = gh.State.ButtonEvent
+	}
+
+	return sensor
+}
+
 func (sensor *sensor) ID() string {
+	sensor.mutex.Lock()
+	defer sensor.mutex.Unlock()
+
 	return sensor.gh.UniqueID
 }
 
 func (sensor *sensor) Name() string {
+	sensor.mutex.Lock()
+	defer sensor.mutex.Unlock()
+
 	return sensor.gh.Name
 }
 
-func (sensor *sensor) SetName(name string) error {
-	return lucifer.ErrUnsupportedOperation
+func (sensor *sensor) DeviceInfo() lucifer.DeviceInfo {
+	sensor.mutex.Lock()
+	defer sensor.mutex.Unlock()
+
+	var battery *int
+	if sensor.gh.Config.Battery != nil {
+		batteryValue := *sensor.gh.Config.Battery
+		battery = &batteryValue
+	}
+
+	info := lucifer.DeviceInfo{
+		Manufacturer:    sensor.gh.ManufacturerName,
+		ModelID:         sensor.gh.ModelID,
+		ProductName:     sensor.gh.ProductName,
+		FirmwareVersion: sensor.gh.SWVersion,
+		Reachable:       sensor.gh.Config.Reachable,
+		Battery:         battery,
+	}
+	// The v1 API does not say when a sensor last reported in, so this is when its state last changed.
+	// A switch that is not pressed for days will look like it has not been seen for days.
+	if sensor.gh.State.LastUpdated.Time != nil {
+		info.LastSeen = *sensor.gh.State.LastUpdated.Time
+	}
+
+	return info
+}
+
+func (sensor *sensor) SetName(ctx context.Context, name string) error {
+	return sensor.bridge.queue.Do(ctx, sensor.path()+"/name", func(ctx context.Context) error {
+		_, err := sensor.bridge.request(ctx, "PUT", sensor.path(), map[string]string{"name": name}, nil)
+		if err != nil {
+			return err
+		}
+
+		sensor.mutex.Lock()
+		sensor.gh.Name = name
+		sensor.mutex.Unlock()
+
+		return nil
+	})
+}
+
+func (sensor *sensor) Kind() lucifer.SensorKind {
+	sensor.mutex.Lock()
+	defer sensor.mutex.Unlock()
+
+	return sensor.kind()
+}
+
+func (sensor *sensor) kind() lucifer.SensorKind {
+	switch sensor.gh.Type {
+	case "ZLLSwitch", "ZGPSwitch":
+		return lucifer.SensorKindButton
+	case "Daylight":
+		return lucifer.SensorKindDaylight
+	case "ZLLPresence", "CLIPPresence":
+		return lucifer.SensorKindPresence
+	case "ZLLTemperature", "CLIPTemperature":
+		return lucifer.SensorKindTemperature
+	case "ZLLLightLevel", "CLIPLightLevel":
+		return lucifer.SensorKindLightLevel
+	case "CLIPOpenClose":
+		return lucifer.SensorKindContact
+	case "CLIPHumidity":
+		return lucifer.SensorKindHumidity
+	default:
+		return lucifer.SensorKindOther
+	}
 }
 
 func (sensor *sensor) IsButton() bool {
-	return sensor.gh.Type == "ZLLSwitch"
+	return sensor.Kind() == lucifer.SensorKindButton
 }
 
 func (sensor *sensor) IsDaylight() bool {
-	return sensor.gh.Type == "Daylight"
+	return sensor.Kind() == lucifer.SensorKindDaylight
 }
 
-func (sensor *sensor) State() (lucifer.SensorState, error) {
-	err := sensor.gh.Refresh()
+func (sensor *sensor) State(ctx context.Context) (lucifer.SensorState, error) {
+	ghSensor, err := sensor.bridge.sensorData(ctx, sensor.index)
 	if err != nil {
 		return lucifer.SensorState{}, err
 	}
 
+	return sensor.update(ghSensor), nil
+}
+
+// update replaces the sensor data with newly fetched data, and decodes its state.
+func (sensor *sensor) update(gh sensorData) lucifer.SensorState {
+	sensor.mutex.Lock()
+	defer sensor.mutex.Unlock()
+
+	sensor.gh = gh
+
+	return sensor.decodeState()
+}
+
+// decodeState converts the last fetched state, and finds the button events since the previous call.
+// The mutex must be held.
+func (sensor *sensor) decodeState() lucifer.SensorState {
 	ghState := sensor.gh.State
 
-	var daylight *bool
+	// Sensors without a last update time have not reported anything yet.
+	var lastUpdated time.Time
+	if ghState.LastUpdated.Time != nil {
+		lastUpdated = *ghState.LastUpdated.Time
+	}
+
 	var buttonEvents []lucifer.SensorStateButtonEvent
 
 	if ghState.ButtonEvent != 0 && !sensor.prevButtonTime.IsZero() {
-		differentTime := !sensor.prevButtonTime.IsZero() && !ghState.LastUpdated.Time.Equal(sensor.prevButtonTime)
+		differentTime := !sensor.prevButtonTime.IsZero() && !lastUpdated.Equal(sensor.prevButtonTime)
 		if differentTime || ghState.ButtonEvent != sensor.prevButtonState {
-			prevButton := sensor.prevButtonState / 1000
-			prevState := sensor.prevButtonState % 1000
-			currButton := ghState.ButtonEvent / 1000
-			currState := ghState.ButtonEvent % 1000
-
-			switch currState {
-			case 0: // Press
-				buttonEvents = append(buttonEvents, lucifer.SensorStateButtonEvent{
-					Kind:   lucifer.ButtonEventPress,
-					Button: int(currButton),
-				})
-			case 1: // Hold
-				buttonEvents = append(buttonEvents, lucifer.SensorStateButtonEvent{
-					Kind:   lucifer.ButtonEventHold,
-					Button: int(currButton),
-				})
-			case 2: // Release (short)
-				if prevState != 0 || prevButton != currButton {
+			if sensor.gh.Type == "ZGPSwitch" {
+				// The Hue Tap only reports presses, with one code per button.
+				if button, ok := tapButtons[ghState.ButtonEvent]; ok {
+					buttonEvents = append(buttonEvents, lucifer.SensorStateButtonEvent{
+						Kind:   lucifer.ButtonEventPress,
+						Button: button,
+					})
+				}
+			} else {
+				prevButton := sensor.prevButtonState / 1000
+				prevState := sensor.prevButtonState % 1000
+				currButton := ghState.ButtonEvent / 1000
+				currState := ghState.ButtonEvent % 1000
+
+				switch currState {
+				case 0: // Press
 					buttonEvents = append(buttonEvents, lucifer.SensorStateButtonEvent{
 						Kind:   lucifer.ButtonEventPress,
 						Button: int(currButton),
 					})
+				case 1: // Hold
+					buttonEvents = append(buttonEvents, lucifer.SensorStateButtonEvent{
+						Kind:   lucifer.ButtonEventHold,
+						Button: int(currButton),
+					})
+				case 2: // Release (short)
+					if prevState != 0 || prevButton != currButton {
+						buttonEvents = append(buttonEvents, lucifer.SensorStateButtonEvent{
+							Kind:   lucifer.ButtonEventPress,
+							Button: int(currButton),
+						})
+					}
+				case 3: // Release (long)
+					buttonEvents = append(buttonEvents, lucifer.SensorStateButtonEvent{
+						Kind:   lucifer.ButtonEventRelease,
+						Button: int(currButton),
+					})
 				}
-			case 3: // Release (long)
-				buttonEvents = append(buttonEvents, lucifer.SensorStateButtonEvent{
-					Kind:   lucifer.ButtonEventRelease,
-					Button: int(currButton),
-				})
 			}
 		}
 
-		sensor.prevButtonTime = *ghState.LastUpdated.Time
+		sensor.prevButtonTime = lastUpdated
 		sensor.prevButtonState = ghState.ButtonEvent
-	} else {
-		if sensor.prevButtonTime.IsZero() {
-			sensor.prevButtonTime = *ghState.LastUpdated.Time
-			sensor.prevButtonState = ghState.ButtonEvent
+	} else if sensor.prevButtonTime.IsZero() {
+		sensor.prevButtonTime = lastUpdated
+		sensor.prevButtonState = ghState.ButtonEvent
+	}
+
+	state := lucifer.SensorState{
+		Time:         lastUpdated,
+		ButtonEvents: buttonEvents,
+	}
+
+	switch sensor.kind() {
+	case lucifer.SensorKindDaylight, lucifer.SensorKindLightLevel:
+		state.Daylight = copyBool(ghState.Daylight)
+		if ghState.LightLevel != nil {
+			// The light level is 10000 log10(lux) + 1.
+			lux := math.Pow(10, float64(*ghState.LightLevel-1)/10000)
+			state.LightLevel = &lux
 		}
+	case lucifer.SensorKindPresence:
+		state.Presence = copyBool(ghState.Presence)
+	case lucifer.SensorKindTemperature:
+		state.Temperature = hundredths(ghState.Temperature)
+	case lucifer.SensorKindContact:
+		state.Open = copyBool(ghState.Open)
+	case lucifer.SensorKindHumidity:
+		state.Humidity = hundredths(ghState.Humidity)
+	}
+
+	return state
+}
 
-		daylightValue := sensor.gh.State.Daylight
-		daylight = &daylightValue
+// copyBool copies the value, so that the state does not share it with the sensor data.
+func copyBool(value *bool) *bool {
+	if value == nil {
+		return nil
 	}
 
-	return lucifer.SensorState{
-		Time:         *sensor.gh.State.LastUpdated.Time,
-		Daylight:     daylight,
-		ButtonEvents: buttonEvents,
-	}, nil
+	copied := *value
+	return &copied
+}
+
+// hundredths converts the value from hundredths of a unit, which the API uses for decimals.
+func hundredths(value *int) *float64 {
+	if value == nil {
+		return nil
+	}
+
+	converted := float64(*value) / 100
+	return &converted
+}
+
+func (sensor *sensor) Forget(ctx context.Context) error {
+	_, err := sensor.bridge.write(ctx, "DELETE", sensor.path(), nil)
+	return err
 }
 
-func (sensor *sensor) Forget() error {
-	panic("implement me")
+// path gets the sensor's path in the API.
+func (sensor *sensor) path() string {
+	return "/sensors/" + strconv.Itoa(sensor.index)
 }
 
 func (sensor *sensor) ButtonEvents(ctx context.Context) <-chan lucifer.SensorStateButtonEvent {
 	channel := make(chan lucifer.SensorStateButtonEvent, 16)
 
+	// Connecting to the event stream can take a while, so it is not done before returning.
 	go func() {
-		unchangedCount := 250
-
-		defer close(channel)
-
-		for {
-			state, err := sensor.State()
-			if err != nil {
+		if sensor.bridge.events != nil {
+			resources, err := sensor.bridge.events.subscribe(ctx)
+			if err == nil {
+				sensor.streamButtonEvents(ctx, resources, channel)
 				return
 			}
+		}
 
-			if len(state.ButtonEvents) > 0 {
-				unchangedCount = 0
-				for _, event := range state.ButtonEvents {
-					select {
-					case channel <- event:
-					default:
-					}
+		sensor.pollButtonEvents(ctx, channel)
+	}()
+
+	return channel
+}
+
+// pollButtonEvents polls the state for button events, for bridges without the v2 event stream.
+func (sensor *sensor) pollButtonEvents(ctx context.Context, channel chan<- lucifer.SensorStateButtonEvent) {
+	unchangedCount := 250
+
+	defer close(channel)
+
+	for {
+		state, err := sensor.State(ctx)
+		if err != nil {
+			return
+		}
+
+		if len(state.ButtonEvents) > 0 {
+			unchangedCount = 0
+			for _, event := range state.ButtonEvents {
+				select {
+				case channel <- event:
+				default:
 				}
-			} else {
-				unchangedCount++
 			}
+		} else {
+			unchangedCount++
+		}
 
-			var waitTime time.Duration
-			if unchangedCount > 50 {
-				waitTime = time.Second / 2
-			} else {
-				waitTime = time.Second / 50
-			}
+		var waitTime time.Duration
+		if unchangedCount > 50 {
+			waitTime = time.Second / 2
+		} else {
+			waitTime = time.Second / 50
+		}
 
-			select {
-			case <-time.After(waitTime):
-			case <-ctx.Done():
-				return
+		select {
+		case <-time.After(waitTime):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (sensor *sensor) streamButtonEvents(ctx context.Context, resources <-chan streamResource, channel chan<- lucifer.SensorStateButtonEvent) {
+	defer close(channel)
+
+	idV1 := fmt.Sprintf("/sensors/%d", sensor.index)
+	lastEvents := make(map[int]string)
+
+	for {
+		var resource streamResource
+		select {
+		case resource = <-resources:
+		case <-ctx.Done():
+			return
+		}
+
+		if resource.IDV1 != idV1 || resource.Button == nil {
+			continue
+		}
+
+		event := lucifer.SensorStateButtonEvent{Button: resource.ControlID}
+		switch resource.Button.LastEvent {
+		case "initial_press":
+			event.Kind = lucifer.ButtonEventPress
+		case "repeat", "long_press":
+			event.Kind = lucifer.ButtonEventHold
+		case "short_release":
+			// Only needed if the press was missed.
+			if lastEvents[resource.ControlID] != "initial_press" {
+				event.Kind = lucifer.ButtonEventPress
 			}
+		case "long_release":
+			event.Kind = lucifer.ButtonEventRelease
 		}
-	}()
+		lastEvents[resource.ControlID] = resource.Button.LastEvent
 
-	return channel
+		if event.Kind == "" {
+			continue
+		}
+
+		select {
+		case channel <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
 }
@@ -0,0 +1,204 @@
+package hue
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+	"time"
+)
+
+type watchedLight struct {
+	state     lucifer.LightState
+	reachable bool
+}
+
+type watchedSensor struct {
+	sensor      *sensor
+	lastUpdated time.Time
+	reachable   bool
+}
+
+// watcher finds changes on the bridge by comparing snapshots.
+type watcher struct {
+	bridge  *bridge
+	channel chan<- lucifer.Event
+
+	lights       map[string]watchedLight
+	sensors      map[string]*watchedSensor
+	disconnected bool
+}
+
+// watch polls the bridge for changes until the context is cancelled. If the v2 event stream is
+// available, any update from it triggers a poll and the regular interval is relaxed.
+func (watcher *watcher) watch(ctx context.Context) {
+	defer close(watcher.channel)
+
+	interval := time.Second
+	var updates <-chan streamResource
+	if watcher.bridge.events != nil {
+		resources, err := watcher.bridge.events.subscribe(ctx)
+		if err == nil {
+			updates = resources
+			interval = time.Second * 10
+		}
+	}
+
+	for {
+		if !watcher.poll(ctx) {
+			return
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-updates:
+			// Let the rest of a burst of updates arrive before polling.
+			timeout := time.After(time.Millisecond * 100)
+		DrainLoop:
+			for {
+				select {
+				case <-updates:
+				case <-timeout:
+					break DrainLoop
+				case <-ctx.Done():
+					return
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll fetches the lights and sensors and sends events for the differences. It returns false if
+// the context was cancelled while sending.
+func (watcher *watcher) poll(ctx context.Context) bool {
+	now := time.Now()
+
+	ghLights, err := watcher.bridge.lightsData(ctx)
+	if err == nil {
+		var ghSensors []sensorData
+		ghSensors, err = watcher.bridge.sensorsData(ctx)
+		if err == nil {
+			watcher.disconnected = false
+
+			return watcher.diffLights(ctx, now, ghLights) && watcher.diffSensors(ctx, now, ghSensors)
+		}
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if watcher.disconnected {
+		return true
+	}
+	watcher.disconnected = true
+
+	return watcher.send(ctx, lucifer.Event{Kind: lucifer.EventBridgeDisconnected, Time: now, Err: err})
+}
+
+func (watcher *watcher) diffLights(ctx context.Context, now time.Time, ghLights []lightData) bool {
+	first := watcher.lights == nil
+	lights := make(map[string]watchedLight, len(ghLights))
+
+	for _, ghLight := range ghLights {
+		id := ghLight.UniqueID
+		state := lightStateOf(ghLight)
+		current := watchedLight{state: state, reachable: ghLight.State.Reachable}
+		lights[id] = current
+
+		if first {
+			continue
+		}
+
+		previous, ok := watcher.lights[id]
+		if !ok {
+			if !watcher.send(ctx, lucifer.Event{Kind: lucifer.EventLightAdded, Time: now, LightID: id, LightState: &state}) {
+				return false
+			}
+
+			continue
+		}
+
+		if previous.state != current.state {
+			if !watcher.send(ctx, lucifer.Event{Kind: lucifer.EventLightStateChanged, Time: now, LightID: id, LightState: &state}) {
+				return false
+			}
+		}
+		if previous.reachable != current.reachable {
+			reachable := current.reachable
+			if !watcher.send(ctx, lucifer.Event{Kind: lucifer.EventReachabilityChanged, Time: now, LightID: id, Reachable: &reachable}) {
+				return false
+			}
+		}
+	}
+
+	for id := range watcher.lights {
+		if _, ok := lights[id]; !ok {
+			if !watcher.send(ctx, lucifer.Event{Kind: lucifer.EventLightRemoved, Time: now, LightID: id}) {
+				return false
+			}
+		}
+	}
+
+	watcher.lights = lights
+
+	return true
+}
+
+func (watcher *watcher) diffSensors(ctx context.Context, now time.Time, ghSensors []sensorData) bool {
+	first := watcher.sensors == nil
+	sensors := make(map[string]*watchedSensor, len(ghSensors))
+
+	for _, ghSensor := range ghSensors {
+		if ghSensor.UniqueID == "" {
+			continue
+		}
+
+		watched, ok := watcher.sensors[ghSensor.UniqueID]
+		if !ok {
+			watched = &watchedSensor{sensor: newSensor(watcher.bridge, ghSensor)}
+		}
+		state := watched.sensor.update(ghSensor)
+		sensors[ghSensor.UniqueID] = watched
+
+		var lastUpdated time.Time
+		if ghSensor.State.LastUpdated.Time != nil {
+			lastUpdated = *ghSensor.State.LastUpdated.Time
+		}
+		reachable := ghSensor.Config.Reachable
+
+		if first || !ok {
+			watched.lastUpdated = lastUpdated
+			watched.reachable = reachable
+			continue
+		}
+
+		if !lastUpdated.Equal(watched.lastUpdated) {
+			watched.lastUpdated = lastUpdated
+
+			if !watcher.send(ctx, lucifer.Event{Kind: lucifer.EventSensorStateChanged, Time: now, SensorID: ghSensor.UniqueID, SensorState: &state}) {
+				return false
+			}
+		}
+		if reachable != watched.reachable {
+			watched.reachable = reachable
+			if !watcher.send(ctx, lucifer.Event{Kind: lucifer.EventReachabilityChanged, Time: now, SensorID: ghSensor.UniqueID, Reachable: &reachable}) {
+				return false
+			}
+		}
+	}
+
+	watcher.sensors = sensors
+
+	return true
+}
+
+func (watcher *watcher) send(ctx context.Context, event lucifer.Event) bool {
+	event.BridgeID = watcher.bridge.ID()
+
+	select {
+	case watcher.channel <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -1,23 +1,95 @@
 package luciferdrivers
 
 import (
+	"context"
 	"github.com/gissleh/lucifer"
 	"github.com/gissleh/lucifer/luciferdrivers/hue"
+	"github.com/gissleh/lucifer/luciferdrivers/virtual"
 )
 
+func init() {
+	Register("hue", func() lucifer.Driver { return hue.New() }, DriverInfo{
+		DisplayName:  "Philips Hue",
+		NeedsPairing: true,
+		Features: []Feature{
+			FeatureDiscovery,
+			FeatureGroups,
+			FeatureScenes,
+			FeatureEvents,
+			FeatureTransitions,
+		},
+	})
+
+	// A new virtual driver has no simulated bridges, so there is nothing for it to discover.
+	Register("virtual", func() lucifer.Driver { return virtual.New() }, DriverInfo{
+		DisplayName: "Virtual",
+		Features: []Feature{
+			FeatureGroups,
+			FeatureScenes,
+			FeatureEvents,
+			FeatureTransitions,
+		},
+	})
+}
+
 // SupportedDrivers gets a list of supported light drivers.
 func SupportedDrivers() []string {
-	return []string{
-		"hue",
+	drivers := Drivers()
+	names := make([]string, len(drivers))
+	for i, info := range drivers {
+		names[i] = info.Name
 	}
+
+	return names
 }
 
 // New creates a new driver.
 func New(kind string) (lucifer.Driver, error) {
-	switch kind {
-	case "hue":
-		return hue.New(), nil
-	default:
+	registryMutex.RLock()
+	reg, ok := registry[kind]
+	registryMutex.RUnlock()
+
+	if !ok {
 		return nil, lucifer.ErrUnsupportedDriver
 	}
+
+	return reg.factory(), nil
+}
+
+// Discover searches the local network for bridges, using the drivers with FeatureDiscovery. An
+// error is only returned if no driver could search.
+func Discover(ctx context.Context) ([]lucifer.DiscoveredBridge, error) {
+	var firstErr error
+	results := make([]lucifer.DiscoveredBridge, 0, 4)
+
+	for _, info := range Drivers() {
+		if !info.HasFeature(FeatureDiscovery) {
+			continue
+		}
+
+		driver, err := New(info.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		bridges, err := driver.Discover(ctx)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		for _, bridge := range bridges {
+			bridge.Driver = info.Name
+			results = append(results, bridge)
+		}
+	}
+
+	if len(results) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
 }
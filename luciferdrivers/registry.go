@@ -0,0 +1,99 @@
+package luciferdrivers
+
+import (
+	"github.com/gissleh/lucifer"
+	"sort"
+	"sync"
+)
+
+// A Factory creates a new instance of a driver.
+type Factory func() lucifer.Driver
+
+// A Feature is something a driver supports beyond the basics.
+type Feature string
+
+const (
+	FeatureDiscovery   Feature = "discovery"
+	FeatureGroups      Feature = "groups"
+	FeatureScenes      Feature = "scenes"
+	FeatureEvents      Feature = "events"
+	FeatureTransitions Feature = "transitions"
+)
+
+// DriverInfo describes a registered driver.
+type DriverInfo struct {
+	// Name is the driver kind passed to New. It is set by Register.
+	Name string `json:"name"`
+
+	// DisplayName is a human readable name for the driver.
+	DisplayName string `json:"displayName"`
+
+	// NeedsPairing is whether SetupBridge requires user interaction, like pressing a link button.
+	NeedsPairing bool `json:"needsPairing"`
+
+	// Features lists the optional features the driver supports.
+	Features []Feature `json:"features"`
+}
+
+// HasFeature gets whether the driver supports the feature.
+func (info *DriverInfo) HasFeature(feature Feature) bool {
+	for _, f := range info.Features {
+		if f == feature {
+			return true
+		}
+	}
+
+	return false
+}
+
+type registration struct {
+	factory Factory
+	info    DriverInfo
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]registration)
+)
+
+// Register makes a driver available by the name. It is meant to be called from the init function of
+// the driver's package. It panics if the name is taken or the factory is nil.
+func Register(name string, factory Factory, info DriverInfo) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if factory == nil {
+		panic("luciferdrivers: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("luciferdrivers: Register called twice for driver " + name)
+	}
+
+	info.Name = name
+	registry[name] = registration{factory: factory, info: info}
+}
+
+// Drivers gets the info of all registered drivers, sorted by name.
+func Drivers() []DriverInfo {
+	registryMutex.RLock()
+	list := make([]DriverInfo, 0, len(registry))
+	for _, reg := range registry {
+		list = append(list, reg.info)
+	}
+	registryMutex.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// Info gets the info of a registered driver.
+func Info(name string) (DriverInfo, bool) {
+	registryMutex.RLock()
+	reg, ok := registry[name]
+	registryMutex.RUnlock()
+
+	return reg.info, ok
+}
@@ -0,0 +1,80 @@
+package luciferdrivers_test
+
+import (
+	"github.com/gissleh/lucifer"
+	"github.com/gissleh/lucifer/luciferdrivers"
+	"github.com/gissleh/lucifer/luciferdrivers/virtual"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	luciferdrivers.Register("test-register", func() lucifer.Driver { return virtual.New() }, luciferdrivers.DriverInfo{
+		Name:        "ignored",
+		DisplayName: "Test",
+		Features:    []luciferdrivers.Feature{luciferdrivers.FeatureGroups},
+	})
+
+	info, ok := luciferdrivers.Info("test-register")
+	require.True(t, ok)
+	assert.Equal(t, "test-register", info.Name, "Register sets the name")
+	assert.Equal(t, "Test", info.DisplayName)
+	assert.True(t, info.HasFeature(luciferdrivers.FeatureGroups))
+	assert.False(t, info.HasFeature(luciferdrivers.FeatureScenes))
+
+	assert.Contains(t, luciferdrivers.Drivers(), info)
+	assert.Contains(t, luciferdrivers.SupportedDrivers(), "test-register")
+
+	driver, err := luciferdrivers.New("test-register")
+	assert.NoError(t, err)
+	assert.NotNil(t, driver)
+
+	_, ok = luciferdrivers.Info("no-such-driver")
+	assert.False(t, ok)
+	_, err = luciferdrivers.New("no-such-driver")
+	assert.Equal(t, lucifer.ErrUnsupportedDriver, err)
+}
+
+func TestRegister_Panics(t *testing.T) {
+	assert.Panics(t, func() {
+		luciferdrivers.Register("test-nil-factory", nil, luciferdrivers.DriverInfo{})
+	}, "nil factory")
+	_, ok := luciferdrivers.Info("test-nil-factory")
+	assert.False(t, ok)
+
+	assert.Panics(t, func() {
+		luciferdrivers.Register("hue", func() lucifer.Driver { return virtual.New() }, luciferdrivers.DriverInfo{})
+	}, "duplicate name")
+	info, _ := luciferdrivers.Info("hue")
+	assert.Equal(t, "Philips Hue", info.DisplayName, "the first registration is kept")
+}
+
+func TestDrivers(t *testing.T) {
+	names := make([]string, 0, 2)
+	for _, info := range luciferdrivers.Drivers() {
+		if info.Name == "hue" || info.Name == "virtual" {
+			names = append(names, info.Name)
+		}
+	}
+	assert.Equal(t, []string{"hue", "virtual"}, names, "sorted by name")
+
+	allFeatures := []luciferdrivers.Feature{
+		luciferdrivers.FeatureDiscovery,
+		luciferdrivers.FeatureGroups,
+		luciferdrivers.FeatureScenes,
+		luciferdrivers.FeatureEvents,
+		luciferdrivers.FeatureTransitions,
+	}
+
+	hueInfo, ok := luciferdrivers.Info("hue")
+	require.True(t, ok)
+	assert.True(t, hueInfo.NeedsPairing)
+	assert.ElementsMatch(t, allFeatures, hueInfo.Features)
+
+	virtualInfo, ok := luciferdrivers.Info("virtual")
+	require.True(t, ok)
+	assert.False(t, virtualInfo.NeedsPairing)
+	assert.ElementsMatch(t, allFeatures[1:], virtualInfo.Features)
+	assert.False(t, virtualInfo.HasFeature(luciferdrivers.FeatureDiscovery), "virtual has nothing to discover")
+}
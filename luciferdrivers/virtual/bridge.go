@@ -0,0 +1,423 @@
+package virtual
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type subscription struct {
+	channel chan lucifer.Event
+}
+
+// Bridge is a simulated bridge.
+type Bridge struct {
+	addr string
+	id   string
+	key  string
+
+	mutex          sync.Mutex
+	name           string
+	lights         []*Light
+	pendingLights  []*Light
+	sensors        []*Sensor
+	pendingSensors []*Sensor
+	groups         []*group
+	scenes         []lucifer.Scene
+	nextID         int
+
+	subMutex      sync.Mutex
+	subscriptions map[*subscription]bool
+}
+
+func newBridge(addr, id, name string) *Bridge {
+	return &Bridge{
+		addr:          addr,
+		id:            id,
+		key:           "virtual-" + id,
+		name:          name,
+		subscriptions: make(map[*subscription]bool),
+	}
+}
+
+// Key gets the key needed to add the bridge with AddBridge.
+func (bridge *Bridge) Key() string {
+	return bridge.key
+}
+
+// AddLight adds a light to the bridge. It starts powered off.
+func (bridge *Bridge) AddLight(id, name string, capabilities lucifer.LightCapabilities) *Light {
+	light := newLight(bridge, id, name, capabilities)
+
+	bridge.mutex.Lock()
+	bridge.lights = append(bridge.lights, light)
+	state := light.state
+	bridge.mutex.Unlock()
+
+	bridge.publish(lucifer.Event{Kind: lucifer.EventLightAdded, LightID: id, LightState: &state})
+
+	return light
+}
+
+// AddPendingLight adds a light that will be found by the next call to DiscoverLights.
+func (bridge *Bridge) AddPendingLight(id, name string, capabilities lucifer.LightCapabilities) *Light {
+	light := newLight(bridge, id, name, capabilities)
+
+	bridge.mutex.Lock()
+	bridge.pendingLights = append(bridge.pendingLights, light)
+	bridge.mutex.Unlock()
+
+	return light
+}
+
+// AddSensor adds a sensor to the bridge.
+func (bridge *Bridge) AddSensor(id, name string, kind lucifer.SensorKind) *Sensor {
+	sensor := newSensor(bridge, id, name, kind)
+
+	bridge.mutex.Lock()
+	bridge.sensors = append(bridge.sensors, sensor)
+	bridge.mutex.Unlock()
+
+	return sensor
+}
+
+// AddPendingSensor adds a sensor that will be found by the next call to DiscoverSensors.
+func (bridge *Bridge) AddPendingSensor(id, name string, kind lucifer.SensorKind) *Sensor {
+	sensor := newSensor(bridge, id, name, kind)
+
+	bridge.mutex.Lock()
+	bridge.pendingSensors = append(bridge.pendingSensors, sensor)
+	bridge.mutex.Unlock()
+
+	return sensor
+}
+
+// Disconnect simulates the connection to the bridge being lost.
+func (bridge *Bridge) Disconnect(err error) {
+	bridge.publish(lucifer.Event{Kind: lucifer.EventBridgeDisconnected, Err: err})
+}
+
+func (bridge *Bridge) ID() string {
+	return bridge.id
+}
+
+func (bridge *Bridge) Name() string {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	return bridge.name
+}
+
+// DeviceInfo describes the simulated bridge, which is always reachable.
+func (bridge *Bridge) DeviceInfo() lucifer.DeviceInfo {
+	return lucifer.DeviceInfo{
+		Manufacturer: "lucifer",
+		ModelID:      "virtual-bridge",
+		ProductName:  "Virtual Bridge",
+		Reachable:    true,
+		LastSeen:     time.Now(),
+	}
+}
+
+func (bridge *Bridge) Light(ctx context.Context, id string) (lucifer.Light, error) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	for _, light := range bridge.lights {
+		if light.id == id {
+			return light, nil
+		}
+	}
+
+	return nil, lucifer.ErrLightNotFound
+}
+
+func (bridge *Bridge) Lights(ctx context.Context) ([]lucifer.Light, error) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	lights := make([]lucifer.Light, len(bridge.lights))
+	for i, light := range bridge.lights {
+		lights[i] = light
+	}
+
+	return lights, nil
+}
+
+func (bridge *Bridge) DiscoverLights(ctx context.Context) ([]lucifer.Light, error) {
+	bridge.mutex.Lock()
+	pending := bridge.pendingLights
+	bridge.pendingLights = nil
+	bridge.lights = append(bridge.lights, pending...)
+	bridge.mutex.Unlock()
+
+	lights := make([]lucifer.Light, len(pending))
+	for i, light := range pending {
+		lights[i] = light
+
+		state, _ := light.State(ctx)
+		bridge.publish(lucifer.Event{Kind: lucifer.EventLightAdded, LightID: light.id, LightState: &state})
+	}
+
+	return lights, nil
+}
+
+func (bridge *Bridge) Sensor(ctx context.Context, id string) (lucifer.Sensor, error) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	for _, sensor := range bridge.sensors {
+		if sensor.id == id {
+			return sensor, nil
+		}
+	}
+
+	return nil, lucifer.ErrSensorNotFound
+}
+
+func (bridge *Bridge) Sensors(ctx context.Context) ([]lucifer.Sensor, error) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	sensors := make([]lucifer.Sensor, len(bridge.sensors))
+	for i, sensor := range bridge.sensors {
+		sensors[i] = sensor
+	}
+
+	return sensors, nil
+}
+
+func (bridge *Bridge) DiscoverSensors(ctx context.Context) ([]lucifer.Sensor, error) {
+	bridge.mutex.Lock()
+	pending := bridge.pendingSensors
+	bridge.pendingSensors = nil
+	bridge.sensors = append(bridge.sensors, pending...)
+	bridge.mutex.Unlock()
+
+	sensors := make([]lucifer.Sensor, len(pending))
+	for i, sensor := range pending {
+		sensors[i] = sensor
+	}
+
+	return sensors, nil
+}
+
+// SearchDevices finds the pending lights and sensors at once. Serial numbers are ignored.
+func (bridge *Bridge) SearchDevices(ctx context.Context, search lucifer.DeviceSearch) <-chan lucifer.SearchEvent {
+	events := make([]lucifer.SearchEvent, 0, 8)
+	done := lucifer.SearchEvent{Kind: lucifer.SearchEventDone, Progress: 1}
+	if search.IncludesLights() {
+		lights, err := bridge.DiscoverLights(ctx)
+		if err != nil {
+			done = lucifer.SearchEvent{Kind: lucifer.SearchEventDone, Err: err}
+		}
+		for _, light := range lights {
+			events = append(events, lucifer.SearchEvent{Kind: lucifer.SearchEventLightFound, Light: light})
+		}
+	}
+	if search.IncludesSensors() && done.Err == nil {
+		sensors, err := bridge.DiscoverSensors(ctx)
+		if err != nil {
+			done = lucifer.SearchEvent{Kind: lucifer.SearchEventDone, Err: err}
+		}
+		for _, sensor := range sensors {
+			events = append(events, lucifer.SearchEvent{Kind: lucifer.SearchEventSensorFound, Sensor: sensor})
+		}
+	}
+	events = append(events, done)
+
+	channel := make(chan lucifer.SearchEvent, len(events))
+	for _, event := range events {
+		channel <- event
+	}
+	close(channel)
+
+	return channel
+}
+
+func (bridge *Bridge) Group(ctx context.Context, id string) (lucifer.Group, error) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	for _, group := range bridge.groups {
+		if group.id == id {
+			return group, nil
+		}
+	}
+
+	return nil, lucifer.ErrGroupNotFound
+}
+
+func (bridge *Bridge) Groups(ctx context.Context) ([]lucifer.Group, error) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	groups := make([]lucifer.Group, len(bridge.groups))
+	for i, group := range bridge.groups {
+		groups[i] = group
+	}
+
+	return groups, nil
+}
+
+func (bridge *Bridge) CreateGroup(ctx context.Context, name string, kind lucifer.GroupKind, lights []lucifer.Light) (lucifer.Group, error) {
+	group := &group{bridge: bridge, name: name, kind: kind, lightIDs: make([]string, len(lights))}
+	for i, light := range lights {
+		group.lightIDs[i] = light.ID()
+	}
+
+	bridge.mutex.Lock()
+	group.id = bridge.newID()
+	bridge.groups = append(bridge.groups, group)
+	bridge.mutex.Unlock()
+
+	return group, nil
+}
+
+func (bridge *Bridge) DeleteGroup(ctx context.Context, id string) error {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	for i, group := range bridge.groups {
+		if group.id == id {
+			bridge.groups = append(bridge.groups[:i], bridge.groups[i+1:]...)
+			return nil
+		}
+	}
+
+	return lucifer.ErrGroupNotFound
+}
+
+func (bridge *Bridge) Scene(ctx context.Context, id string) (lucifer.Scene, error) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	for _, scene := range bridge.scenes {
+		if scene.ID == id {
+			return scene, nil
+		}
+	}
+
+	return lucifer.Scene{}, lucifer.ErrSceneNotFound
+}
+
+func (bridge *Bridge) Scenes(ctx context.Context) ([]lucifer.Scene, error) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	scenes := make([]lucifer.Scene, len(bridge.scenes))
+	copy(scenes, bridge.scenes)
+
+	return scenes, nil
+}
+
+func (bridge *Bridge) CreateScene(ctx context.Context, name string, lights []lucifer.Light) (lucifer.Scene, error) {
+	scene, err := lucifer.CaptureScene(ctx, name, lights)
+	if err != nil {
+		return lucifer.Scene{}, err
+	}
+
+	bridge.mutex.Lock()
+	scene.ID = bridge.newID()
+	bridge.scenes = append(bridge.scenes, scene)
+	bridge.mutex.Unlock()
+
+	return scene, nil
+}
+
+func (bridge *Bridge) RecallScene(ctx context.Context, id string) error {
+	scene, err := bridge.Scene(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Recalling without the ID sets the lights one by one.
+	scene.ID = ""
+
+	return lucifer.RecallScene(ctx, bridge, scene)
+}
+
+func (bridge *Bridge) DeleteScene(ctx context.Context, id string) error {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	for i, scene := range bridge.scenes {
+		if scene.ID == id {
+			bridge.scenes = append(bridge.scenes[:i], bridge.scenes[i+1:]...)
+			return nil
+		}
+	}
+
+	return lucifer.ErrSceneNotFound
+}
+
+// Events gets the bridge's events until the context is cancelled. Every change to the simulation is
+// sent, but subscribers that fall too far behind miss events rather than holding it up.
+func (bridge *Bridge) Events(ctx context.Context) <-chan lucifer.Event {
+	sub := &subscription{channel: make(chan lucifer.Event, 64)}
+
+	bridge.subMutex.Lock()
+	bridge.subscriptions[sub] = true
+	bridge.subMutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		bridge.subMutex.Lock()
+		delete(bridge.subscriptions, sub)
+		close(sub.channel)
+		bridge.subMutex.Unlock()
+	}()
+
+	return sub.channel
+}
+
+func (bridge *Bridge) publish(event lucifer.Event) {
+	event.BridgeID = bridge.id
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	bridge.subMutex.Lock()
+	defer bridge.subMutex.Unlock()
+
+	for sub := range bridge.subscriptions {
+		select {
+		case sub.channel <- event:
+		default:
+		}
+	}
+}
+
+func (bridge *Bridge) removeLight(light *Light) {
+	bridge.mutex.Lock()
+	for i := range bridge.lights {
+		if bridge.lights[i] == light {
+			bridge.lights = append(bridge.lights[:i], bridge.lights[i+1:]...)
+			break
+		}
+	}
+	bridge.mutex.Unlock()
+
+	bridge.publish(lucifer.Event{Kind: lucifer.EventLightRemoved, LightID: light.id})
+}
+
+func (bridge *Bridge) removeSensor(sensor *Sensor) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+
+	for i := range bridge.sensors {
+		if bridge.sensors[i] == sensor {
+			bridge.sensors = append(bridge.sensors[:i], bridge.sensors[i+1:]...)
+			break
+		}
+	}
+}
+
+// newID gets the next ID for groups and scenes. The mutex must be held.
+func (bridge *Bridge) newID() string {
+	bridge.nextID++
+	return strconv.Itoa(bridge.nextID)
+}
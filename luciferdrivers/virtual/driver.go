@@ -0,0 +1,140 @@
+// Package virtual is a driver for simulated bridges, lights and sensors. It is meant for tests and
+// demos, and the simulation can be controlled through the exported types.
+package virtual
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+	"sync"
+)
+
+// Driver is a driver for simulated bridges.
+type Driver struct {
+	mutex      sync.Mutex
+	simulated  map[string]*Bridge
+	bridgeList []*Bridge
+	bridgeMap  map[string]*Bridge
+}
+
+// New creates a driver with no simulated bridges.
+func New() *Driver {
+	return &Driver{
+		simulated:  make(map[string]*Bridge, 8),
+		bridgeList: make([]*Bridge, 0, 8),
+		bridgeMap:  make(map[string]*Bridge, 8),
+	}
+}
+
+// SimulateBridge makes a simulated bridge available at the address, so that it can be discovered and
+// set up. It returns the existing bridge if there already is one at the address.
+func (driver *Driver) SimulateBridge(addr, id, name string) *Bridge {
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	if bridge, ok := driver.simulated[addr]; ok {
+		return bridge
+	}
+
+	bridge := newBridge(addr, id, name)
+	driver.simulated[addr] = bridge
+
+	return bridge
+}
+
+// SetupBridge sets up the simulated bridge at the address. If there is none, one is simulated with
+// the address as its ID.
+func (driver *Driver) SetupBridge(ctx context.Context, addr string) (lucifer.Bridge, string, error) {
+	bridge := driver.SimulateBridge(addr, addr, "Virtual Bridge "+addr)
+
+	driver.add(bridge)
+
+	return bridge, bridge.key, nil
+}
+
+func (driver *Driver) AddBridge(ctx context.Context, addr, key string) (lucifer.Bridge, error) {
+	driver.mutex.Lock()
+	bridge := driver.simulated[addr]
+	driver.mutex.Unlock()
+
+	if bridge == nil {
+		return nil, lucifer.ErrBridgeNotFound
+	}
+	if key != bridge.key {
+		return nil, lucifer.ErrUnauthorized
+	}
+
+	driver.add(bridge)
+
+	return bridge, nil
+}
+
+func (driver *Driver) RemoveBridge(ctx context.Context, id string) error {
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	bridge := driver.bridgeMap[id]
+	if bridge == nil {
+		return lucifer.ErrBridgeNotFound
+	}
+
+	for i := range driver.bridgeList {
+		if driver.bridgeList[i] == bridge {
+			driver.bridgeList = append(driver.bridgeList[:i], driver.bridgeList[i+1:]...)
+			break
+		}
+	}
+	delete(driver.bridgeMap, id)
+
+	return nil
+}
+
+func (driver *Driver) Bridge(id string) lucifer.Bridge {
+	driver.mutex.Lock()
+	bridge := driver.bridgeMap[id]
+	driver.mutex.Unlock()
+
+	if bridge == nil {
+		return nil
+	}
+
+	return bridge
+}
+
+func (driver *Driver) Bridges() []lucifer.Bridge {
+	driver.mutex.Lock()
+	list := make([]lucifer.Bridge, 0, len(driver.bridgeList))
+	for _, bridge := range driver.bridgeList {
+		list = append(list, bridge)
+	}
+	driver.mutex.Unlock()
+
+	return list
+}
+
+func (driver *Driver) Discover(ctx context.Context) ([]lucifer.DiscoveredBridge, error) {
+	driver.mutex.Lock()
+	list := make([]lucifer.DiscoveredBridge, 0, len(driver.simulated))
+	for addr, bridge := range driver.simulated {
+		list = append(list, lucifer.DiscoveredBridge{
+			Address: addr,
+			ID:      bridge.id,
+			Model:   "virtual",
+			Name:    bridge.Name(),
+		})
+	}
+	driver.mutex.Unlock()
+
+	return list, nil
+}
+
+func (driver *Driver) add(bridge *Bridge) {
+	driver.mutex.Lock()
+	defer driver.mutex.Unlock()
+
+	if _, ok := driver.bridgeMap[bridge.id]; ok {
+		return
+	}
+
+	driver.bridgeList = append(driver.bridgeList, bridge)
+	driver.bridgeMap[bridge.id] = bridge
+}
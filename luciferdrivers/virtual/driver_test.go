@@ -0,0 +1,57 @@
+package virtual_test
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+	"github.com/gissleh/lucifer/luciferdrivers/virtual"
+	"github.com/gissleh/lucifer/lucifertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDriver(t *testing.T) {
+	lucifertest.TestDriver(t, lucifertest.Harness{
+		New: func(t *testing.T) (lucifer.Driver, string) {
+			driver := virtual.New()
+
+			bridge := driver.SimulateBridge("10.0.0.2", "bridge-1", "Test Bridge")
+			bridge.AddLight("color", "Color Light", virtual.ColorCapabilities)
+			bridge.AddLight("ambiance", "White Ambiance Light", virtual.WhiteAmbianceCapabilities)
+			bridge.AddLight("dimmable", "Dimmable Light", virtual.DimmableCapabilities)
+			bridge.AddLight("plug", "Plug", virtual.OnOffCapabilities)
+			bridge.AddSensor("switch", "Switch", lucifer.SensorKindButton)
+			bridge.AddSensor("daylight", "Daylight", lucifer.SensorKindDaylight)
+			bridge.AddSensor("motion", "Motion Sensor", lucifer.SensorKindPresence)
+			bridge.AddSensor("temperature", "Temperature Sensor", lucifer.SensorKindTemperature)
+
+			return driver, "10.0.0.2"
+		},
+		PressButton: func(t *testing.T, sensor lucifer.Sensor, button int) {
+			sensor.(*virtual.Sensor).PressButton(button)
+		},
+	})
+}
+
+func TestBridge_Events_Unread(t *testing.T) {
+	bridge := virtual.New().SimulateBridge("10.0.0.2", "bridge-1", "Test Bridge")
+	light := bridge.AddLight("color", "Color Light", virtual.ColorCapabilities)
+	sensor := bridge.AddSensor("switch", "Switch", lucifer.SensorKindButton)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// Neither of these are read from.
+	bridge.Events(ctx)
+	sensor.ButtonEvents(ctx)
+
+	for i := 0; i < 200; i++ {
+		require.NoError(t, light.SetState(ctx, lucifer.LightState{Power: i%2 == 0, Brightness: 1}))
+		sensor.PressButton(1)
+	}
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	assert.Equal(t, context.Canceled, light.SetState(cancelled, lucifer.LightState{}))
+}
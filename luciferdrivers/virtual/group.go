@@ -0,0 +1,59 @@
+package virtual
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+)
+
+type group struct {
+	bridge   *Bridge
+	id       string
+	name     string
+	kind     lucifer.GroupKind
+	lightIDs []string
+}
+
+func (group *group) ID() string {
+	return group.id
+}
+
+func (group *group) Name() string {
+	return group.name
+}
+
+func (group *group) Kind() lucifer.GroupKind {
+	return group.kind
+}
+
+func (group *group) Lights(ctx context.Context) ([]lucifer.Light, error) {
+	group.bridge.mutex.Lock()
+	defer group.bridge.mutex.Unlock()
+
+	lights := make([]lucifer.Light, 0, len(group.lightIDs))
+	for _, light := range group.bridge.lights {
+		for _, id := range group.lightIDs {
+			if light.id == id {
+				lights = append(lights, light)
+				break
+			}
+		}
+	}
+
+	return lights, nil
+}
+
+func (group *group) SetState(ctx context.Context, state lucifer.LightState) error {
+	lights, err := group.Lights(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, light := range lights {
+		err := light.SetState(ctx, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package virtual
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+)
+
+var (
+	// ColorCapabilities are the capabilities of a full color light with white ambiance.
+	ColorCapabilities = lucifer.LightCapabilities{
+		Color:            true,
+		ColorTemperature: true,
+		MinKelvin:        2000,
+		MaxKelvin:        6500,
+		Gamut:            lucifer.GamutC,
+		BrightnessSteps:  254,
+		Effects:          []string{"colorloop"},
+		Transition:       true,
+	}
+
+	// WhiteAmbianceCapabilities are the capabilities of a light with adjustable color temperature.
+	WhiteAmbianceCapabilities = lucifer.LightCapabilities{
+		ColorTemperature: true,
+		MinKelvin:        2200,
+		MaxKelvin:        6500,
+		BrightnessSteps:  254,
+		Transition:       true,
+	}
+
+	// DimmableCapabilities are the capabilities of a white light that can only be dimmed.
+	DimmableCapabilities = lucifer.LightCapabilities{
+		BrightnessSteps: 254,
+		Transition:      true,
+	}
+
+	// OnOffCapabilities are the capabilities of a light, or plug, that can only be switched on and off.
+	OnOffCapabilities = lucifer.LightCapabilities{}
+)
+
+// Light is a simulated light.
+type Light struct {
+	bridge       *Bridge
+	id           string
+	capabilities lucifer.LightCapabilities
+
+	name      string
+	state     lucifer.LightState
+	effect    string
+	reachable bool
+}
+
+func newLight(bridge *Bridge, id, name string, capabilities lucifer.LightCapabilities) *Light {
+	light := &Light{
+		bridge:       bridge,
+		id:           id,
+		name:         name,
+		capabilities: capabilities,
+		reachable:    true,
+	}
+
+	light.state.Brightness = 1
+	if capabilities.ColorTemperature {
+		light.state.Color.SetKelvin(capabilities.ClampKelvin(2700))
+	} else {
+		light.state.Color.SetHSV(0, 0, 1)
+	}
+
+	return light
+}
+
+// SetReachable simulates the bridge losing or regaining contact with the light.
+func (light *Light) SetReachable(reachable bool) {
+	light.bridge.mutex.Lock()
+	changed := light.reachable != reachable
+	light.reachable = reachable
+	light.bridge.mutex.Unlock()
+
+	if changed {
+		light.bridge.publish(lucifer.Event{Kind: lucifer.EventReachabilityChanged, LightID: light.id, Reachable: &reachable})
+	}
+}
+
+// Reachable gets whether the light is reachable.
+func (light *Light) Reachable() bool {
+	light.bridge.mutex.Lock()
+	defer light.bridge.mutex.Unlock()
+
+	return light.reachable
+}
+
+func (light *Light) ID() string {
+	return light.id
+}
+
+func (light *Light) Name() string {
+	light.bridge.mutex.Lock()
+	defer light.bridge.mutex.Unlock()
+
+	return light.name
+}
+
+func (light *Light) SetName(ctx context.Context, name string) error {
+	light.bridge.mutex.Lock()
+	light.name = name
+	light.bridge.mutex.Unlock()
+
+	return nil
+}
+
+func (light *Light) DeviceInfo() lucifer.DeviceInfo {
+	light.bridge.mutex.Lock()
+	defer light.bridge.mutex.Unlock()
+
+	return lucifer.DeviceInfo{
+		Manufacturer: "lucifer",
+		ModelID:      "virtual-light",
+		ProductName:  "Virtual Light",
+		Reachable:    light.reachable,
+	}
+}
+
+func (light *Light) Capabilities() lucifer.LightCapabilities {
+	return light.capabilities
+}
+
+// Refresh does nothing, since the simulated state is always current.
+func (light *Light) Refresh(ctx context.Context) error {
+	return nil
+}
+
+func (light *Light) State(ctx context.Context) (lucifer.LightState, error) {
+	light.bridge.mutex.Lock()
+	defer light.bridge.mutex.Unlock()
+
+	return light.state, nil
+}
+
+// SetState changes the state as far as the capabilities allow. Transitions complete immediately.
+func (light *Light) SetState(ctx context.Context, state lucifer.LightState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return light.change(func(lucifer.LightState) lucifer.LightState {
+		return state
+	})
+}
+
+// Update changes the state as far as the capabilities allow. Transitions complete immediately.
+func (light *Light) Update(ctx context.Context, update lucifer.LightUpdate) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return light.change(update.Apply)
+}
+
+// SetEffect starts or stops the native effect, and turns the light on when starting it.
+func (light *Light) SetEffect(ctx context.Context, name string) error {
+	if name != "" && !light.capabilities.HasEffect(name) {
+		return lucifer.ErrUnsupportedOperation
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := light.change(func(current lucifer.LightState) lucifer.LightState {
+		if name != "" {
+			current.Power = true
+		}
+
+		return current
+	})
+
+	light.bridge.mutex.Lock()
+	light.effect = name
+	light.bridge.mutex.Unlock()
+
+	return err
+}
+
+// Effect gets the native effect that is running, or an empty string if there is none.
+func (light *Light) Effect() string {
+	light.bridge.mutex.Lock()
+	defer light.bridge.mutex.Unlock()
+
+	return light.effect
+}
+
+func (light *Light) Forget(ctx context.Context) error {
+	light.bridge.removeLight(light)
+	return nil
+}
+
+// change changes the state based on the current state, and publishes the change.
+func (light *Light) change(cb func(current lucifer.LightState) lucifer.LightState) error {
+	light.bridge.mutex.Lock()
+	newState := light.render(cb(light.state))
+	changed := newState != light.state
+	light.state = newState
+	light.effect = ""
+	light.bridge.mutex.Unlock()
+
+	if changed {
+		light.bridge.publish(lucifer.Event{Kind: lucifer.EventLightStateChanged, LightID: light.id, LightState: &newState})
+	}
+
+	return nil
+}
+
+// render gets the state the light would end up in. The mutex must be held.
+func (light *Light) render(state lucifer.LightState) lucifer.LightState {
+	state.Transition = 0
+
+	if !light.capabilities.Dimmable() {
+		state.Brightness = 1
+	}
+
+	if state.Color.K != 0 || !light.capabilities.Color {
+		if light.capabilities.ColorTemperature {
+			// Lights without full color show the closest white.
+			state.Color.SetKelvin(light.capabilities.ClampKelvin(state.Color.Kelvin()))
+		} else if !light.capabilities.Color {
+			state.Color = light.state.Color
+		}
+	}
+
+	return state
+}
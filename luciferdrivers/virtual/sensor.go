@@ -0,0 +1,224 @@
+package virtual
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+	"time"
+)
+
+// Sensor is a simulated sensor.
+type Sensor struct {
+	bridge *Bridge
+	id     string
+	kind   lucifer.SensorKind
+
+	name         string
+	lastUpdated  time.Time
+	reading      lucifer.SensorState
+	battery      *int
+	buttonEvents []lucifer.SensorStateButtonEvent
+}
+
+func newSensor(bridge *Bridge, id, name string, kind lucifer.SensorKind) *Sensor {
+	sensor := &Sensor{
+		bridge:      bridge,
+		id:          id,
+		kind:        kind,
+		name:        name,
+		lastUpdated: time.Now(),
+	}
+
+	// The reading starts at zero for the sensor's kind.
+	switch kind {
+	case lucifer.SensorKindDaylight:
+		sensor.reading.Daylight = new(bool)
+	case lucifer.SensorKindPresence:
+		sensor.reading.Presence = new(bool)
+	case lucifer.SensorKindTemperature:
+		sensor.reading.Temperature = new(float64)
+	case lucifer.SensorKindLightLevel:
+		sensor.reading.LightLevel = new(float64)
+	case lucifer.SensorKindContact:
+		sensor.reading.Open = new(bool)
+	case lucifer.SensorKindHumidity:
+		sensor.reading.Humidity = new(float64)
+	}
+
+	return sensor
+}
+
+// InjectButtonEvents simulates button events on the sensor.
+func (sensor *Sensor) InjectButtonEvents(events ...lucifer.SensorStateButtonEvent) {
+	sensor.bridge.mutex.Lock()
+	sensor.lastUpdated = time.Now()
+	sensor.buttonEvents = append(sensor.buttonEvents, events...)
+	state := lucifer.SensorState{
+		Time:         sensor.lastUpdated,
+		ButtonEvents: append([]lucifer.SensorStateButtonEvent(nil), events...),
+	}
+	sensor.bridge.mutex.Unlock()
+
+	sensor.bridge.publish(lucifer.Event{Kind: lucifer.EventSensorStateChanged, SensorID: sensor.id, SensorState: &state})
+}
+
+// PressButton simulates a short press of the button.
+func (sensor *Sensor) PressButton(button int) {
+	sensor.InjectButtonEvents(lucifer.SensorStateButtonEvent{Button: button, Kind: lucifer.ButtonEventPress})
+}
+
+// SetDaylight simulates the daylight sensor changing state.
+func (sensor *Sensor) SetDaylight(daylight bool) {
+	sensor.setReading(func(reading *lucifer.SensorState) {
+		reading.Daylight = &daylight
+	})
+}
+
+// SetPresence simulates the motion sensor detecting, or no longer detecting, motion.
+func (sensor *Sensor) SetPresence(presence bool) {
+	sensor.setReading(func(reading *lucifer.SensorState) {
+		reading.Presence = &presence
+	})
+}
+
+// SetTemperature simulates the temperature changing, in degrees Celsius.
+func (sensor *Sensor) SetTemperature(temperature float64) {
+	sensor.setReading(func(reading *lucifer.SensorState) {
+		reading.Temperature = &temperature
+	})
+}
+
+// SetLightLevel simulates the light level changing, in lux.
+func (sensor *Sensor) SetLightLevel(lux float64) {
+	sensor.setReading(func(reading *lucifer.SensorState) {
+		reading.LightLevel = &lux
+	})
+}
+
+// SetOpen simulates the contact sensor's door or window opening or closing.
+func (sensor *Sensor) SetOpen(open bool) {
+	sensor.setReading(func(reading *lucifer.SensorState) {
+		reading.Open = &open
+	})
+}
+
+// SetHumidity simulates the relative humidity changing, in percent.
+func (sensor *Sensor) SetHumidity(humidity float64) {
+	sensor.setReading(func(reading *lucifer.SensorState) {
+		reading.Humidity = &humidity
+	})
+}
+
+// SetBattery simulates the battery level changing, in percent.
+func (sensor *Sensor) SetBattery(battery int) {
+	sensor.bridge.mutex.Lock()
+	sensor.battery = &battery
+	sensor.bridge.mutex.Unlock()
+}
+
+// setReading changes the sensor's reading and publishes the change.
+func (sensor *Sensor) setReading(cb func(reading *lucifer.SensorState)) {
+	sensor.bridge.mutex.Lock()
+	sensor.lastUpdated = time.Now()
+	cb(&sensor.reading)
+	state := sensor.reading
+	state.Time = sensor.lastUpdated
+	sensor.bridge.mutex.Unlock()
+
+	sensor.bridge.publish(lucifer.Event{Kind: lucifer.EventSensorStateChanged, SensorID: sensor.id, SensorState: &state})
+}
+
+func (sensor *Sensor) ID() string {
+	return sensor.id
+}
+
+func (sensor *Sensor) Kind() lucifer.SensorKind {
+	return sensor.kind
+}
+
+// DeviceInfo describes the simulated sensor, which is always reachable and has no battery until
+// SetBattery is called.
+func (sensor *Sensor) DeviceInfo() lucifer.DeviceInfo {
+	sensor.bridge.mutex.Lock()
+	defer sensor.bridge.mutex.Unlock()
+
+	info := lucifer.DeviceInfo{
+		Manufacturer: "lucifer",
+		ModelID:      "virtual-sensor",
+		ProductName:  "Virtual Sensor",
+		Reachable:    true,
+		LastSeen:     sensor.lastUpdated,
+	}
+	if sensor.battery != nil {
+		battery := *sensor.battery
+		info.Battery = &battery
+	}
+
+	return info
+}
+
+func (sensor *Sensor) IsButton() bool {
+	return sensor.kind == lucifer.SensorKindButton
+}
+
+func (sensor *Sensor) IsDaylight() bool {
+	return sensor.kind == lucifer.SensorKindDaylight
+}
+
+func (sensor *Sensor) Name() string {
+	sensor.bridge.mutex.Lock()
+	defer sensor.bridge.mutex.Unlock()
+
+	return sensor.name
+}
+
+func (sensor *Sensor) SetName(ctx context.Context, name string) error {
+	sensor.bridge.mutex.Lock()
+	sensor.name = name
+	sensor.bridge.mutex.Unlock()
+
+	return nil
+}
+
+// State gets the sensor's state, along with the button events since the last call.
+func (sensor *Sensor) State(ctx context.Context) (lucifer.SensorState, error) {
+	sensor.bridge.mutex.Lock()
+	defer sensor.bridge.mutex.Unlock()
+
+	state := sensor.reading
+	state.Time = sensor.lastUpdated
+	state.ButtonEvents = sensor.buttonEvents
+	sensor.buttonEvents = nil
+
+	return state, nil
+}
+
+func (sensor *Sensor) ButtonEvents(ctx context.Context) <-chan lucifer.SensorStateButtonEvent {
+	channel := make(chan lucifer.SensorStateButtonEvent, 16)
+	events := sensor.bridge.Events(ctx)
+
+	go func() {
+		defer close(channel)
+
+		for event := range events {
+			if event.Kind != lucifer.EventSensorStateChanged || event.SensorID != sensor.id {
+				continue
+			}
+
+			// The events are dropped if the channel is full, so that the bridge's events keep
+			// being read.
+			for _, buttonEvent := range event.SensorState.ButtonEvents {
+				select {
+				case channel <- buttonEvent:
+				default:
+				}
+			}
+		}
+	}()
+
+	return channel
+}
+
+func (sensor *Sensor) Forget(ctx context.Context) error {
+	sensor.bridge.removeSensor(sensor)
+	return nil
+}
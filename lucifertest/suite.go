@@ -0,0 +1,536 @@
+// Package lucifertest contains a conformance suite for lucifer drivers. It checks the behaviour that
+// the interfaces in the lucifer package promise, and is meant to be run from a driver's tests.
+package lucifertest
+
+import (
+	"context"
+	"errors"
+	"github.com/gissleh/lucifer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+// Timeout is how long the suite waits for anything asynchronous, like events and closing channels.
+var Timeout = time.Second * 5
+
+// Harness sets up the driver under test.
+type Harness struct {
+	// New creates a new driver, and gets the address of a bridge that can be set up without user
+	// interaction. The bridge must have at least one light.
+	New func(t *testing.T) (driver lucifer.Driver, addr string)
+
+	// PressButton simulates a short press on a button sensor. If nil, button event delivery is not tested.
+	PressButton func(t *testing.T, sensor lucifer.Sensor, button int)
+}
+
+// TestDriver runs the conformance suite against a driver.
+func TestDriver(t *testing.T, harness Harness) {
+	t.Run("Bridges", func(t *testing.T) {
+		testBridges(t, harness)
+	})
+	t.Run("Lights", func(t *testing.T) {
+		testLights(t, harness)
+	})
+	t.Run("SearchDevices", func(t *testing.T) {
+		testSearchDevices(t, harness)
+	})
+	t.Run("LightState", func(t *testing.T) {
+		testLightState(t, harness)
+	})
+	t.Run("LightUpdate", func(t *testing.T) {
+		testLightUpdate(t, harness)
+	})
+	t.Run("Effects", func(t *testing.T) {
+		testEffects(t, harness)
+	})
+	t.Run("Groups", func(t *testing.T) {
+		testGroups(t, harness)
+	})
+	t.Run("Scenes", func(t *testing.T) {
+		testScenes(t, harness)
+	})
+	t.Run("Sensors", func(t *testing.T) {
+		testSensors(t, harness)
+	})
+	t.Run("Events", func(t *testing.T) {
+		testEvents(t, harness)
+	})
+}
+
+func setup(t *testing.T, harness Harness) (lucifer.Driver, lucifer.Bridge, string) {
+	driver, addr := harness.New(t)
+
+	bridge, key, err := driver.SetupBridge(context.Background(), addr)
+	require.NoError(t, err)
+	require.NotNil(t, bridge)
+
+	return driver, bridge, key
+}
+
+func testBridges(t *testing.T, harness Harness) {
+	ctx := context.Background()
+	driver, addr := harness.New(t)
+	bridge, key, err := driver.SetupBridge(ctx, addr)
+	require.NoError(t, err)
+	id := bridge.ID()
+
+	assert.NotEmpty(t, id, "bridge ID")
+	assert.True(t, bridge.DeviceInfo().Reachable, "bridge reachable after setup")
+	if assert.NotNil(t, driver.Bridge(id), "Bridge(id) after setup") {
+		assert.Equal(t, id, driver.Bridge(id).ID(), "Bridge(id) after setup")
+	}
+	assert.Len(t, driver.Bridges(), 1, "Bridges() after setup")
+	assert.Nil(t, driver.Bridge("not-a-bridge"), "Bridge(id) for unknown ID")
+
+	require.NoError(t, driver.RemoveBridge(ctx, id))
+	assert.Nil(t, driver.Bridge(id), "Bridge(id) after RemoveBridge")
+	assert.Empty(t, driver.Bridges(), "Bridges() after RemoveBridge")
+	assert.Equal(t, lucifer.ErrBridgeNotFound, driver.RemoveBridge(ctx, id), "RemoveBridge twice")
+
+	_, err = driver.AddBridge(ctx, addr, "not-the-key")
+	assert.True(t, errors.Is(err, lucifer.ErrUnauthorized), "AddBridge with wrong key returned %v", err)
+
+	bridge, err = driver.AddBridge(ctx, addr, key)
+	require.NoError(t, err, "AddBridge with key from SetupBridge")
+	assert.Equal(t, id, bridge.ID(), "bridge ID after AddBridge")
+	assert.NotNil(t, driver.Bridge(id), "Bridge(id) after AddBridge")
+}
+
+func testLights(t *testing.T, harness Harness) {
+	ctx := context.Background()
+	_, bridge, _ := setup(t, harness)
+
+	lights, err := bridge.Lights(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, lights, "the bridge must have lights")
+
+	for _, light := range lights {
+		found, err := bridge.Light(ctx, light.ID())
+		if assert.NoError(t, err, "Light(id)") {
+			assert.Equal(t, light.ID(), found.ID(), "Light(id)")
+		}
+
+		assert.True(t, light.DeviceInfo().Reachable, "%s reachable", light.ID())
+
+		capabilities := light.Capabilities()
+		if capabilities.ColorTemperature {
+			assert.True(t, capabilities.MinKelvin > 0, "MinKelvin for %s", light.ID())
+			assert.True(t, capabilities.MinKelvin <= capabilities.MaxKelvin, "kelvin range for %s", light.ID())
+		}
+		assert.Equal(t, capabilities.Color, capabilities.Gamut != lucifer.GamutNone, "gamut for %s", light.ID())
+	}
+
+	_, err = bridge.Light(ctx, "not-a-light")
+	assert.True(t, errors.Is(err, lucifer.ErrLightNotFound), "Light(id) for unknown ID returned %v", err)
+
+	err = lights[0].SetName(ctx, "Conformance Test")
+	if !errors.Is(err, lucifer.ErrUnsupportedOperation) {
+		assert.NoError(t, err, "SetName")
+
+		light, err := bridge.Light(ctx, lights[0].ID())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Conformance Test", light.Name(), "Name after SetName")
+		}
+	}
+}
+
+func testSearchDevices(t *testing.T, harness Harness) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	_, bridge, _ := setup(t, harness)
+
+	var last lucifer.SearchEvent
+	for event := range bridge.SearchDevices(ctx, lucifer.DeviceSearch{}) {
+		switch event.Kind {
+		case lucifer.SearchEventLightFound:
+			assert.NotNil(t, event.Light, "light in %s event", event.Kind)
+		case lucifer.SearchEventSensorFound:
+			assert.NotNil(t, event.Sensor, "sensor in %s event", event.Kind)
+		}
+
+		last = event
+	}
+
+	require.NoError(t, ctx.Err(), "SearchDevices did not finish in time")
+	assert.Equal(t, lucifer.SearchEventDone, last.Kind, "last event")
+	assert.NoError(t, last.Err, "search error")
+}
+
+func testLightState(t *testing.T, harness Harness) {
+	ctx := context.Background()
+	_, bridge, _ := setup(t, harness)
+
+	lights, err := bridge.Lights(ctx)
+	require.NoError(t, err)
+
+	for _, light := range lights {
+		capabilities := light.Capabilities()
+
+		states := []lucifer.LightState{{Power: false}}
+		if capabilities.Dimmable() {
+			states = append(states, lucifer.LightState{Power: true, Brightness: 0.5, Color: lucifer.MustParseColor("#ffffff")})
+		}
+		if capabilities.ColorTemperature {
+			states = append(states, lucifer.LightState{Power: true, Brightness: 1, Color: lucifer.MustParseColor("2700k")})
+		}
+		if capabilities.Color {
+			states = append(states, lucifer.LightState{Power: true, Brightness: 1, Color: lucifer.MustParseColor("#ff0000")})
+		}
+
+		for _, state := range states {
+			require.NoError(t, light.SetState(ctx, state), "SetState on %s", light.ID())
+
+			found, err := bridge.Light(ctx, light.ID())
+			require.NoError(t, err)
+
+			actual, err := found.State(ctx)
+			require.NoError(t, err, "State on %s", light.ID())
+
+			assert.Equal(t, state.Power, actual.Power, "power of %s", light.ID())
+			if !state.Power {
+				continue
+			}
+
+			if capabilities.Dimmable() {
+				assert.InDelta(t, state.Brightness, actual.Brightness, 0.01, "brightness of %s", light.ID())
+			}
+			if state.Color.K != 0 {
+				assert.InDelta(t, state.Color.K, actual.Color.K, 50, "kelvin of %s", light.ID())
+			} else if capabilities.Color {
+				h, s, _ := state.Color.HSV()
+				ah, as, _ := actual.Color.HSV()
+				if s > 0.1 {
+					// The hue of white is undefined.
+					assert.True(t, hueDistance(h, ah) < 2, "hue of %s: expected %f, got %f", light.ID(), h, ah)
+				}
+				assert.InDelta(t, s, as, 0.02, "saturation of %s", light.ID())
+			}
+		}
+	}
+
+	// Changes made through another value for the same light must not be missed.
+	stale := lights[0]
+	other, err := bridge.Light(ctx, stale.ID())
+	require.NoError(t, err)
+	require.NoError(t, other.SetState(ctx, lucifer.LightState{Power: true, Brightness: 1}))
+
+	require.NoError(t, stale.Refresh(ctx))
+	state, err := stale.State(ctx)
+	require.NoError(t, err)
+	assert.True(t, state.Power, "power after Refresh")
+
+	require.NoError(t, stale.SetState(ctx, lucifer.LightState{Power: false}))
+	require.NoError(t, other.Refresh(ctx))
+	state, err = other.State(ctx)
+	require.NoError(t, err)
+	assert.False(t, state.Power, "power after SetState on the other value")
+}
+
+func testLightUpdate(t *testing.T, harness Harness) {
+	ctx := context.Background()
+	_, bridge, _ := setup(t, harness)
+
+	lights, err := bridge.Lights(ctx)
+	require.NoError(t, err)
+
+	for _, light := range lights {
+		capabilities := light.Capabilities()
+		if !capabilities.Dimmable() {
+			continue
+		}
+
+		initial := lucifer.LightState{Power: true, Brightness: 1, Color: lucifer.MustParseColor("#ffffff")}
+		if capabilities.ColorTemperature {
+			initial.Color = lucifer.MustParseColor("2700k")
+		}
+		require.NoError(t, light.SetState(ctx, initial), "SetState on %s", light.ID())
+		before, err := light.State(ctx)
+		require.NoError(t, err)
+
+		update := lucifer.LightUpdate{}
+		update.SetBrightness(0.3)
+		require.NoError(t, light.Update(ctx, update), "Update on %s", light.ID())
+
+		after, err := light.State(ctx)
+		require.NoError(t, err)
+		assert.True(t, after.Power, "power of %s after Update", light.ID())
+		assert.InDelta(t, 0.3, after.Brightness, 0.01, "brightness of %s after Update", light.ID())
+		if capabilities.ColorTemperature {
+			assert.InDelta(t, before.Color.K, after.Color.K, 50, "kelvin of %s after Update", light.ID())
+		}
+
+		update = lucifer.LightUpdate{}
+		update.SetPower(false)
+		require.NoError(t, light.Update(ctx, update), "Update on %s", light.ID())
+
+		after, err = light.State(ctx)
+		require.NoError(t, err)
+		assert.False(t, after.Power, "power of %s after Update", light.ID())
+	}
+}
+
+func testEffects(t *testing.T, harness Harness) {
+	ctx := context.Background()
+	_, bridge, _ := setup(t, harness)
+
+	lights, err := bridge.Lights(ctx)
+	require.NoError(t, err)
+
+	for _, light := range lights {
+		capabilities := light.Capabilities()
+		err := light.SetEffect(ctx, "no-such-effect")
+		assert.True(t, errors.Is(err, lucifer.ErrUnsupportedOperation), "SetEffect on %s returned %v", light.ID(), err)
+
+		for _, effect := range capabilities.Effects {
+			require.NoError(t, light.SetEffect(ctx, effect), "SetEffect(%q) on %s", effect, light.ID())
+
+			state, err := light.State(ctx)
+			require.NoError(t, err)
+			assert.True(t, state.Power, "power of %s during %s", light.ID(), effect)
+
+			require.NoError(t, light.SetEffect(ctx, ""), "stopping %s on %s", effect, light.ID())
+		}
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, time.Millisecond*300)
+	defer cancel()
+
+	err = lucifer.RunEffect(runCtx, lucifer.NewRateLimiter(50), lucifer.ColorLoop(1, time.Second), lights...)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "RunEffect returned %v", err)
+}
+
+func testGroups(t *testing.T, harness Harness) {
+	ctx := context.Background()
+	_, bridge, _ := setup(t, harness)
+
+	lights, err := bridge.Lights(ctx)
+	require.NoError(t, err)
+
+	group, err := bridge.CreateGroup(ctx, "Conformance Test", lucifer.GroupKindLightGroup, lights[:1])
+	if errors.Is(err, lucifer.ErrUnsupportedOperation) {
+		t.Skip("groups are not supported")
+	}
+	require.NoError(t, err, "CreateGroup")
+
+	found, err := bridge.Group(ctx, group.ID())
+	require.NoError(t, err, "Group(id) after CreateGroup")
+	assert.Equal(t, "Conformance Test", found.Name())
+	assert.Equal(t, lucifer.GroupKindLightGroup, found.Kind())
+
+	members, err := found.Lights(ctx)
+	require.NoError(t, err, "Lights in group")
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, lights[0].ID(), members[0].ID())
+	}
+
+	require.NoError(t, found.SetState(ctx, lucifer.LightState{Power: true, Brightness: 1}), "SetState on group")
+	light, err := bridge.Light(ctx, lights[0].ID())
+	require.NoError(t, err)
+	state, err := light.State(ctx)
+	require.NoError(t, err)
+	assert.True(t, state.Power, "power of light after SetState on group")
+
+	require.NoError(t, bridge.DeleteGroup(ctx, group.ID()), "DeleteGroup")
+	_, err = bridge.Group(ctx, group.ID())
+	assert.True(t, errors.Is(err, lucifer.ErrGroupNotFound), "Group(id) after DeleteGroup returned %v", err)
+}
+
+func testScenes(t *testing.T, harness Harness) {
+	ctx := context.Background()
+	_, bridge, _ := setup(t, harness)
+
+	lights, err := bridge.Lights(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, lights[0].SetState(ctx, lucifer.LightState{Power: true, Brightness: 1}))
+
+	scene, err := bridge.CreateScene(ctx, "Conformance Test", lights[:1])
+	if errors.Is(err, lucifer.ErrUnsupportedOperation) {
+		t.Skip("scenes are not supported")
+	}
+	require.NoError(t, err, "CreateScene")
+	assert.NotEmpty(t, scene.ID, "scene ID")
+
+	found, err := bridge.Scene(ctx, scene.ID)
+	require.NoError(t, err, "Scene(id) after CreateScene")
+	assert.Equal(t, "Conformance Test", found.Name)
+	assert.Contains(t, found.States, lights[0].ID(), "light in scene")
+
+	require.NoError(t, lights[0].SetState(ctx, lucifer.LightState{Power: false}))
+	require.NoError(t, bridge.RecallScene(ctx, scene.ID), "RecallScene")
+
+	light, err := bridge.Light(ctx, lights[0].ID())
+	require.NoError(t, err)
+	state, err := light.State(ctx)
+	require.NoError(t, err)
+	assert.True(t, state.Power, "power of light after RecallScene")
+
+	require.NoError(t, bridge.DeleteScene(ctx, scene.ID), "DeleteScene")
+	_, err = bridge.Scene(ctx, scene.ID)
+	assert.True(t, errors.Is(err, lucifer.ErrSceneNotFound), "Scene(id) after DeleteScene returned %v", err)
+}
+
+func testSensors(t *testing.T, harness Harness) {
+	ctx := context.Background()
+	_, bridge, _ := setup(t, harness)
+
+	sensors, err := bridge.Sensors(ctx)
+	require.NoError(t, err)
+
+	for _, sensor := range sensors {
+		found, err := bridge.Sensor(ctx, sensor.ID())
+		if assert.NoError(t, err, "Sensor(id)") {
+			assert.Equal(t, sensor.ID(), found.ID(), "Sensor(id)")
+		}
+
+		kind := sensor.Kind()
+		assert.Equal(t, kind == lucifer.SensorKindButton, sensor.IsButton(), "IsButton on %s", sensor.ID())
+		assert.Equal(t, kind == lucifer.SensorKindDaylight, sensor.IsDaylight(), "IsDaylight on %s", sensor.ID())
+
+		state, err := sensor.State(ctx)
+		if assert.NoError(t, err, "State on %s", sensor.ID()) {
+			switch kind {
+			case lucifer.SensorKindDaylight:
+				assert.NotNil(t, state.Daylight, "Daylight of %s", sensor.ID())
+			case lucifer.SensorKindPresence:
+				assert.NotNil(t, state.Presence, "Presence of %s", sensor.ID())
+			case lucifer.SensorKindTemperature:
+				assert.NotNil(t, state.Temperature, "Temperature of %s", sensor.ID())
+			case lucifer.SensorKindLightLevel:
+				assert.NotNil(t, state.LightLevel, "LightLevel of %s", sensor.ID())
+			case lucifer.SensorKindContact:
+				assert.NotNil(t, state.Open, "Open of %s", sensor.ID())
+			case lucifer.SensorKindHumidity:
+				assert.NotNil(t, state.Humidity, "Humidity of %s", sensor.ID())
+			}
+		}
+
+		if !sensor.IsButton() {
+			continue
+		}
+
+		eventCtx, cancel := context.WithCancel(ctx)
+		events := sensor.ButtonEvents(eventCtx)
+
+		if harness.PressButton != nil {
+			harness.PressButton(t, sensor, 1)
+
+			select {
+			case event, ok := <-events:
+				if assert.True(t, ok, "ButtonEvents closed early") {
+					assert.Equal(t, lucifer.SensorStateButtonEvent{Button: 1, Kind: lucifer.ButtonEventPress}, event)
+				}
+			case <-time.After(Timeout):
+				t.Error("ButtonEvents did not get the press")
+			}
+		}
+
+		cancel()
+		assertCloses(t, events, "ButtonEvents after cancel")
+	}
+
+	_, err = bridge.Sensor(ctx, "not-a-sensor")
+	assert.True(t, errors.Is(err, lucifer.ErrSensorNotFound), "Sensor(id) for unknown ID returned %v", err)
+
+	if len(sensors) == 0 {
+		return
+	}
+
+	err = sensors[0].SetName(ctx, "Conformance Test")
+	if !errors.Is(err, lucifer.ErrUnsupportedOperation) {
+		assert.NoError(t, err, "SetName on sensor")
+
+		sensor, err := bridge.Sensor(ctx, sensors[0].ID())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Conformance Test", sensor.Name(), "Name after SetName")
+		}
+	}
+
+	err = sensors[0].Forget(ctx)
+	if !errors.Is(err, lucifer.ErrUnsupportedOperation) {
+		assert.NoError(t, err, "Forget on sensor")
+
+		_, err = bridge.Sensor(ctx, sensors[0].ID())
+		assert.True(t, errors.Is(err, lucifer.ErrSensorNotFound), "Sensor(id) after Forget returned %v", err)
+	}
+}
+
+func testEvents(t *testing.T, harness Harness) {
+	ctx, cancel := context.WithCancel(context.Background())
+	_, bridge, _ := setup(t, harness)
+
+	lights, err := bridge.Lights(ctx)
+	require.NoError(t, err)
+	require.NoError(t, lights[0].SetState(ctx, lucifer.LightState{Power: false}))
+
+	events := bridge.Events(ctx)
+
+	// Give drivers that poll a chance to take their first snapshot.
+	time.Sleep(time.Millisecond * 100)
+
+	require.NoError(t, lights[0].SetState(ctx, lucifer.LightState{Power: true, Brightness: 1}))
+
+	timeout := time.After(Timeout)
+WaitLoop:
+	for {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "Events closed early")
+
+			assert.Equal(t, bridge.ID(), event.BridgeID, "bridge ID in event")
+			if event.Kind == lucifer.EventLightStateChanged && event.LightID == lights[0].ID() {
+				if assert.NotNil(t, event.LightState) {
+					assert.True(t, event.LightState.Power, "power in event")
+				}
+
+				break WaitLoop
+			}
+		case <-timeout:
+			t.Error("Events did not get the state change")
+			break WaitLoop
+		}
+	}
+
+	cancel()
+
+	// The channel might have buffered events.
+	deadline := time.After(Timeout)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Error("Events was not closed after cancel")
+			return
+		}
+	}
+}
+
+func assertCloses(t *testing.T, channel <-chan lucifer.SensorStateButtonEvent, msg string) {
+	deadline := time.After(Timeout)
+	for {
+		select {
+		case _, ok := <-channel:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Error(msg, "was not closed")
+			return
+		}
+	}
+}
+
+func hueDistance(a, b float64) float64 {
+	d := math.Abs(a - b)
+	if d > 180 {
+		d = 360 - d
+	}
+
+	return d
+}
@@ -0,0 +1,66 @@
+package lucifer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A RateLimiter spaces out commands to a bridge. Everything sending commands to the same bridge
+// should share one, e.g. Hue bridges handle about 10 light commands per second.
+type RateLimiter struct {
+	interval time.Duration
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+// NewRateLimiter creates a rate limiter that allows the number of commands per second. If perSecond
+// is zero or less, there is no limit and it returns nil.
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	return &RateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Interval gets the minimum time between commands.
+func (limiter *RateLimiter) Interval() time.Duration {
+	if limiter == nil {
+		return 0
+	}
+
+	return limiter.interval
+}
+
+// Wait blocks until the next command may be sent, or the context is done. A nil limiter never waits.
+func (limiter *RateLimiter) Wait(ctx context.Context) error {
+	if limiter == nil {
+		return ctx.Err()
+	}
+
+	limiter.mutex.Lock()
+	now := time.Now()
+	at := limiter.next
+	if at.Before(now) {
+		at = now
+	}
+	limiter.next = at.Add(limiter.interval)
+	limiter.mutex.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,18 @@
+package lucifer_test
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter_Unlimited(t *testing.T) {
+	for _, perSecond := range []float64{0, -1} {
+		limiter := lucifer.NewRateLimiter(perSecond)
+		assert.Nil(t, limiter)
+		assert.Equal(t, time.Duration(0), limiter.Interval())
+		assert.NoError(t, limiter.Wait(context.Background()))
+	}
+}
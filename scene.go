@@ -0,0 +1,67 @@
+package lucifer
+
+import (
+	"context"
+	"errors"
+)
+
+// A Scene is a set of light states that can be recalled together.
+type Scene struct {
+	// ID is the scene's ID on the bridge, or empty if it is not stored on one.
+	ID string `json:"id"`
+
+	// Name is the scene's name.
+	Name string `json:"name"`
+
+	// States is the light states by light ID. Drivers may leave it empty when listing scenes.
+	States map[string]LightState `json:"states"`
+}
+
+// CaptureScene creates a scene from the current states of the lights. It is not stored on any bridge.
+func CaptureScene(ctx context.Context, name string, lights []Light) (Scene, error) {
+	scene := Scene{
+		Name:   name,
+		States: make(map[string]LightState, len(lights)),
+	}
+
+	for _, light := range lights {
+		state, err := light.State(ctx)
+		if err != nil {
+			return Scene{}, err
+		}
+
+		scene.States[light.ID()] = state
+	}
+
+	return scene, nil
+}
+
+// RecallScene applies the scene. Scenes stored on the bridge are recalled by the bridge, while the
+// lights are set one by one for scenes that are not or when the driver lacks scene storage.
+func RecallScene(ctx context.Context, bridge Bridge, scene Scene) error {
+	if scene.ID != "" {
+		err := bridge.RecallScene(ctx, scene.ID)
+		if !errors.Is(err, ErrUnsupportedOperation) {
+			return err
+		}
+	}
+
+	lights, err := bridge.Lights(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, light := range lights {
+		state, ok := scene.States[light.ID()]
+		if !ok {
+			continue
+		}
+
+		err := light.SetState(ctx, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package lucifer
+
+import "encoding/json"
+
+// A DeviceSearch is a search for new lights and sensors. If neither Lights nor Sensors is set, it
+// searches for both.
+type DeviceSearch struct {
+	Lights  bool `json:"lights"`
+	Sensors bool `json:"sensors"`
+
+	// Serials are the serial numbers of lights that cannot be found otherwise, like lights that
+	// belong to another bridge. Drivers that cannot search for serial numbers ignore them.
+	Serials []string `json:"serials,omitempty"`
+}
+
+// IncludesLights gets whether the search is for lights.
+func (search *DeviceSearch) IncludesLights() bool {
+	return search.Lights || !search.Sensors
+}
+
+// IncludesSensors gets whether the search is for sensors.
+func (search *DeviceSearch) IncludesSensors() bool {
+	return search.Sensors || !search.Lights
+}
+
+// SearchEventKind is the kind of a search event.
+type SearchEventKind string
+
+const (
+	// SearchEventLightFound is sent when a new light has been found.
+	SearchEventLightFound SearchEventKind = "LightFound"
+	// SearchEventSensorFound is sent when a new sensor has been found.
+	SearchEventSensorFound SearchEventKind = "SensorFound"
+	// SearchEventProgress is sent periodically while the search is running.
+	SearchEventProgress SearchEventKind = "Progress"
+	// SearchEventDone is sent when the search is over, and is the last event. If the context is
+	// cancelled while the events are not being read, the channel may be closed without it.
+	SearchEventDone SearchEventKind = "Done"
+)
+
+// A SearchEvent is sent while searching for devices. Which fields are set depend on the kind. In JSON,
+// the light and sensor are given by their IDs.
+type SearchEvent struct {
+	Kind   SearchEventKind `json:"kind"`
+	Light  Light           `json:"-"`
+	Sensor Sensor          `json:"-"`
+
+	// Progress is an estimate of how far the search has come, from 0 to 1.
+	Progress float64 `json:"progress"`
+
+	// Err is set on the done event if the search failed.
+	Err error `json:"-"`
+}
+
+func (event SearchEvent) MarshalJSON() ([]byte, error) {
+	type searchEvent SearchEvent
+
+	data := struct {
+		searchEvent
+		LightID  string `json:"lightId,omitempty"`
+		SensorID string `json:"sensorId,omitempty"`
+	}{searchEvent: searchEvent(event)}
+	if event.Light != nil {
+		data.LightID = event.Light.ID()
+	}
+	if event.Sensor != nil {
+		data.SensorID = event.Sensor.ID()
+	}
+
+	return json.Marshal(data)
+}
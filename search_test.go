@@ -0,0 +1,34 @@
+package lucifer_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gissleh/lucifer"
+	"github.com/gissleh/lucifer/luciferdrivers/virtual"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDeviceSearch_JSON(t *testing.T) {
+	data, err := json.Marshal(lucifer.DeviceSearch{Lights: true, Serials: []string{"ABC123"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"lights":true,"sensors":false,"serials":["ABC123"]}`, string(data))
+
+	var search lucifer.DeviceSearch
+	require.NoError(t, json.Unmarshal([]byte(`{"sensors":true}`), &search))
+	assert.Equal(t, lucifer.DeviceSearch{Sensors: true}, search)
+}
+
+func TestSearchEvent_JSON(t *testing.T) {
+	bridge := virtual.New().SimulateBridge("10.0.0.2", "bridge-1", "Test Bridge")
+	light := bridge.AddLight("a", "A", virtual.DimmableCapabilities)
+
+	data, err := json.Marshal(lucifer.SearchEvent{Kind: lucifer.SearchEventLightFound, Light: light, Progress: 0.5})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"kind":"LightFound","progress":0.5,"lightId":"`+light.ID()+`"}`, string(data))
+
+	data, err = json.Marshal(lucifer.SearchEvent{Kind: lucifer.SearchEventDone, Progress: 1, Err: context.Canceled})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"kind":"Done","progress":1}`, string(data))
+}
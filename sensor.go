@@ -2,10 +2,27 @@ package lucifer
 
 import "context"
 
+// SensorKind is what a sensor measures.
+type SensorKind string
+
+const (
+	SensorKindButton      SensorKind = "button"
+	SensorKindDaylight    SensorKind = "daylight"
+	SensorKindPresence    SensorKind = "presence"
+	SensorKindTemperature SensorKind = "temperature"
+	SensorKindLightLevel  SensorKind = "lightLevel"
+	SensorKindContact     SensorKind = "contact"
+	SensorKindHumidity    SensorKind = "humidity"
+	SensorKindOther       SensorKind = "other"
+)
+
 type Sensor interface {
 	// ID gets the sensor's ID.
 	ID() string
 
+	// Kind gets what the sensor measures, which decides the fields set in its state.
+	Kind() SensorKind
+
 	// Gets whether the sensor is a button
 	IsButton() bool
 
@@ -15,15 +32,18 @@ type Sensor interface {
 	// Name gets the sensor's name.
 	Name() string
 
+	// DeviceInfo describes the sensor's hardware as of when its state was last fetched.
+	DeviceInfo() DeviceInfo
+
 	// SetName sets the sensor's name
-	SetName(name string) error
+	SetName(ctx context.Context, name string) error
 
 	// State is the sensor's state.
-	State() (SensorState, error)
+	State(ctx context.Context) (SensorState, error)
 
 	// SubscribeButtonEvents subscribes to button events.
 	ButtonEvents(ctx context.Context) <-chan SensorStateButtonEvent
 
 	// Forget forgets the sensor.
-	Forget() error
+	Forget(ctx context.Context) error
 }
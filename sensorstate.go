@@ -15,8 +15,24 @@ type SensorStateButtonEvent struct {
 	Kind   SensorStateButtonEventKind `json:"kind"`
 }
 
+// SensorState is a sensor's state. The fields that don't apply to the sensor's kind are nil.
 type SensorState struct {
 	Time         time.Time                `json:"time"`
 	Daylight     *bool                    `json:"daylight"`
 	ButtonEvents []SensorStateButtonEvent `json:"buttonEvents"`
+
+	// Presence is whether motion has been detected recently.
+	Presence *bool `json:"presence"`
+
+	// Temperature is in degrees Celsius.
+	Temperature *float64 `json:"temperature"`
+
+	// LightLevel is in lux.
+	LightLevel *float64 `json:"lightLevel"`
+
+	// Open is whether a contact sensor's door or window is open.
+	Open *bool `json:"open"`
+
+	// Humidity is the relative humidity in percent.
+	Humidity *float64 `json:"humidity"`
 }
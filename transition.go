@@ -0,0 +1,69 @@
+package lucifer
+
+import (
+	"context"
+	"time"
+)
+
+// FadeStepInterval is the time between each step when Fade emulates a transition.
+var FadeStepInterval = time.Second / 10
+
+// Fade changes the light's state over the duration of state.Transition. If the light cannot fade
+// natively, the transition is emulated by stepping through intermediate states.
+func Fade(ctx context.Context, light Light, state LightState) error {
+	capabilities := light.Capabilities()
+	if state.Transition <= 0 || capabilities.Transition {
+		return light.SetState(ctx, state)
+	}
+
+	from, err := light.State(ctx)
+	if err != nil {
+		return err
+	}
+	if !from.Power {
+		from.Brightness = 0
+	}
+
+	steps := int(state.Transition / FadeStepInterval)
+	if steps < 1 {
+		steps = 1
+	}
+
+	ticker := time.NewTicker(FadeStepInterval)
+	defer ticker.Stop()
+
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		err := light.SetState(ctx, interpolateState(from, state, float64(i)/float64(steps)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// interpolateState gets the state t (0..1) of the way between from and to.
+func interpolateState(from, to LightState, t float64) LightState {
+	if t >= 1 {
+		to.Transition = 0
+		return to
+	}
+
+	result := LightState{
+		Power:      from.Power || to.Power,
+		Brightness: from.Brightness + (to.Brightness-from.Brightness)*t,
+	}
+	if !to.Power {
+		result.Brightness = from.Brightness * (1 - t)
+	}
+
+	result.Color = from.Color.Lerp(to.Color, t)
+
+	return result
+}
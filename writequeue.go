@@ -0,0 +1,126 @@
+package lucifer
+
+import (
+	"context"
+	"sync"
+)
+
+// A WriteQueue sends writes to a bridge one at a time, within its rate limit. A write that is still
+// waiting when another with the same key is queued is replaced by it, so bursts of changes to the
+// same light only send the last one.
+type WriteQueue struct {
+	limiter *RateLimiter
+
+	mutex   sync.Mutex
+	writes  []*queuedWrite
+	running bool
+}
+
+type queuedWrite struct {
+	key   string
+	write func(ctx context.Context) error
+	done  chan struct{}
+	err   error
+
+	// ctx is cancelled once none of the callers are waiting for the write anymore.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// QueueReporter is implemented by bridges that send their writes through a WriteQueue, so that the
+// backlog can be shown.
+type QueueReporter interface {
+	// QueueLen gets the number of writes waiting to be sent.
+	QueueLen() int
+}
+
+// NewWriteQueue creates a write queue. The limiter may be nil to only send writes one at a time.
+func NewWriteQueue(limiter *RateLimiter) *WriteQueue {
+	return &WriteQueue{limiter: limiter}
+}
+
+// Do queues the write and waits until it is sent, returning its error. If it is replaced by a later
+// write with the same key, it waits for that one instead. Writes with an empty key are never replaced.
+// The write's context is cancelled when the contexts of all the callers waiting for it are done.
+func (queue *WriteQueue) Do(ctx context.Context, key string, write func(ctx context.Context) error) error {
+	queue.mutex.Lock()
+	var queued *queuedWrite
+	if key != "" {
+		for i, other := range queue.writes {
+			if other.key == key {
+				// It moves to the back, so that it is not sent before writes queued before it.
+				queued = other
+				queue.writes = append(queue.writes[:i], queue.writes[i+1:]...)
+				break
+			}
+		}
+	}
+	if queued == nil {
+		writeCtx, cancel := context.WithCancel(context.Background())
+		queued = &queuedWrite{key: key, ctx: writeCtx, cancel: cancel, done: make(chan struct{})}
+	}
+	queued.waiters++
+	queued.write = write
+	queue.writes = append(queue.writes, queued)
+
+	if !queue.running {
+		queue.running = true
+		go queue.run()
+	}
+	queue.mutex.Unlock()
+
+	select {
+	case <-queued.done:
+		return queued.err
+	case <-ctx.Done():
+		queue.mutex.Lock()
+		queued.waiters--
+		if queued.waiters == 0 {
+			queued.cancel()
+		}
+		queue.mutex.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+// Limiter gets the queue's rate limiter, which may be nil.
+func (queue *WriteQueue) Limiter() *RateLimiter {
+	return queue.limiter
+}
+
+// Len gets the number of writes waiting to be sent.
+func (queue *WriteQueue) Len() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	return len(queue.writes)
+}
+
+// run sends the writes until the queue is empty.
+func (queue *WriteQueue) run() {
+	for {
+		queue.mutex.Lock()
+		if len(queue.writes) == 0 {
+			queue.running = false
+			queue.mutex.Unlock()
+			return
+		}
+		queued := queue.writes[0]
+		queue.writes = queue.writes[1:]
+		queue.mutex.Unlock()
+
+		// Writes nobody is waiting for anymore are dropped without using up the rate limit.
+		queued.err = queued.ctx.Err()
+		if queued.err == nil {
+			queued.err = queue.limiter.Wait(queued.ctx)
+		}
+		if queued.err == nil {
+			queued.err = queued.write(queued.ctx)
+		}
+
+		queued.cancel()
+		close(queued.done)
+	}
+}
@@ -0,0 +1,99 @@
+package lucifer_test
+
+import (
+	"context"
+	"github.com/gissleh/lucifer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteQueue_Do(t *testing.T) {
+	queue := lucifer.NewWriteQueue(nil)
+	release := make(chan struct{})
+	sent := make(chan string, 16)
+	write := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			sent <- name
+			if name == "blocker" {
+				<-release
+			}
+
+			return nil
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	do := func(key, name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, queue.Do(context.Background(), key, write(name)), name)
+		}()
+	}
+
+	do("", "blocker")
+	assert.Equal(t, "blocker", <-sent)
+
+	do("a", "a1")
+	require.Eventually(t, func() bool { return queue.Len() == 1 }, time.Second, time.Millisecond)
+	do("b", "b")
+	require.Eventually(t, func() bool { return queue.Len() == 2 }, time.Second, time.Millisecond)
+	do("a", "a2")
+	time.Sleep(time.Millisecond * 20)
+	assert.Equal(t, 2, queue.Len(), "a2 replaces a1")
+
+	close(release)
+	wg.Wait()
+	close(sent)
+
+	names := make([]string, 0, 2)
+	for name := range sent {
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"b", "a2"}, names)
+	assert.Equal(t, 0, queue.Len())
+}
+
+func TestWriteQueue_Do_Cancel(t *testing.T) {
+	queue := lucifer.NewWriteQueue(lucifer.NewRateLimiter(1))
+	require.NoError(t, queue.Do(context.Background(), "", func(ctx context.Context) error { return nil }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	err := queue.Do(ctx, "", func(ctx context.Context) error { return nil })
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestWriteQueue_Do_CancelMerged(t *testing.T) {
+	queue := lucifer.NewWriteQueue(nil)
+	release := make(chan struct{})
+	go func() {
+		_ = queue.Do(context.Background(), "", func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+	}()
+	require.Eventually(t, func() bool { return queue.Len() == 0 }, time.Second, time.Millisecond)
+
+	first := make(chan error)
+	go func() {
+		first <- queue.Do(context.Background(), "a", func(ctx context.Context) error { return ctx.Err() })
+	}()
+	require.Eventually(t, func() bool { return queue.Len() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	second := make(chan error)
+	go func() {
+		second <- queue.Do(ctx, "a", func(ctx context.Context) error { return ctx.Err() })
+	}()
+	time.Sleep(time.Millisecond * 20)
+	cancel()
+	assert.Equal(t, context.Canceled, <-second)
+
+	close(release)
+	assert.NoError(t, <-first, "the first caller is not affected by the second one giving up")
+}